@@ -0,0 +1,94 @@
+package main
+
+// The check command, verifying a decomposition of a hypergraph produced earlier, or by another tool
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+// runCheck implements the check command, with the arguments following it
+func runCheck(args []string) int {
+	flagSet := flag.NewFlagSet("check", flag.ContinueOnError)
+	graphPath := flagSet.String("graph", "", "input (for format see hyperbench.dbai.tuwien.ac.at/downloads/manual.pdf)")
+	pace := flagSet.Bool("pace", false, "Use PACE 2019 format for graphs (see pacechallenge.org/2019/htd/htd_format/)")
+	gr := flagSet.Bool("gr", false, "Use PACE 2017 format for graphs (see pacechallenge.org/2017/treewidth/)")
+	gmlPath := flagSet.String("gml", "", "file of the HD to check, in the GML format written by decompose -gml")
+	tdPath := flagSet.String("td", "", "file of the tree decomposition to check, in PACE 2017 format")
+	width := flagSet.Int("width", 0, "if positive, the largest width accepted (the treewidth, with -td)")
+
+	flagSet.Usage = func() {
+		fmt.Fprintln(flagSet.Output(), "Usage of log-k-decomp check: -graph <file> (-gml <file> | -td <file>) [flags]")
+		flagSet.PrintDefaults()
+	}
+	if code, stop := parseFlags(flagSet, args); stop {
+		return code
+	}
+	if *graphPath == "" || (*gmlPath == "") == (*tdPath == "") {
+		fmt.Fprint(os.Stderr, "The flag -graph and exactly one of -gml or -td need to be specified.\n\n")
+		flagSet.Usage()
+		return exitError
+	}
+	if *pace && *gr {
+		fmt.Fprintln(os.Stderr, "the flags -pace and -gr can't be combined")
+		return exitError
+	}
+
+	dat, err := logk.ReadInput(*graphPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	var graph Graph
+	var encoding logk.Encoding
+	switch {
+	case *gr:
+		graph, encoding, err = logk.GetGraphGR(string(dat))
+	case *pace:
+		graph, encoding, err = logk.GetGraphPACE(string(dat))
+	default:
+		graph, encoding, err = logk.GetGraph(string(dat))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	path := *gmlPath
+	if path == "" {
+		path = *tdPath
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	defer f.Close()
+
+	var decomp Decomp
+	var decompWidth int
+	if *tdPath != "" {
+		decomp, err = logk.ReadTD(f, graph, encoding)
+		decompWidth = logk.TreeWidth(decomp)
+	} else {
+		decomp, err = logk.ReadGML(f, graph, encoding)
+		decompWidth = decomp.CheckWidth()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Couldn't read", path+":", err)
+		return exitError
+	}
+
+	correct := decomp.Correct(graph)
+	fmt.Println("Width: ", decompWidth)
+	fmt.Println("Correct: ", correct)
+
+	if !correct || (*width > 0 && decompWidth > *width) {
+		return exitNotFound
+	}
+	return exitOK
+}
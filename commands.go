@@ -0,0 +1,148 @@
+package main
+
+// The commands of the tool, each with its own flags, and the exit codes they report
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Exit codes of the commands
+const (
+	exitOK       = 0 // a decomposition was found, or the command succeeded
+	exitError    = 1 // the input or the flags were invalid
+	exitNotFound = 2 // no decomposition of the given width exists, or the one checked is incorrect
+	exitStopped  = 3 // the search was stopped by the timeout or the memory limit
+)
+
+// exitRank orders the exit codes, so that the code of a run over several inputs is the most severe one
+var exitRank = map[int]int{exitOK: 0, exitNotFound: 1, exitStopped: 2, exitError: 3}
+
+// worseExit returns the more severe of two exit codes
+func worseExit(a, b int) int {
+	if exitRank[b] > exitRank[a] {
+		return b
+	}
+	return a
+}
+
+// A command of the tool, run with the arguments following its name, returning the exit code
+type command struct {
+	name        string
+	description string
+	run         func(args []string) int
+}
+
+// commands lists the commands of the tool, the first one being the default
+var commands []command
+
+func init() {
+	commands = []command{
+		{"decompose", "search for a hypertree decomposition (or tree decomposition, with -tw) of a hypergraph", runDecompose},
+		{"check", "check a decomposition of a hypergraph, given in GML or PACE 2017 format", runCheck},
+		{"stats", "report structural properties of hypergraphs", runStats},
+		{"convert", "translate hypergraphs between the HyperBench, PACE, gr and JSON formats", runConvert},
+		{"generate", "produce seeded random and structured hypergraphs", runGenerate},
+		{"serve", "run as a long-lived HTTP service, decomposing hypergraphs as jobs", runServe},
+	}
+}
+
+// usage prints the commands of the tool
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage of log-k-decomp: <command> [flags]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.description)
+	}
+	fmt.Fprintln(w, "\nWithout a command, the flags are those of decompose. Run 'log-k-decomp <command> -h' for the flags of a command.")
+	fmt.Fprintln(w, "\nExit codes:")
+	fmt.Fprintln(w, "  0  a decomposition was found, or the command succeeded")
+	fmt.Fprintln(w, "  1  the input or the flags were invalid")
+	fmt.Fprintln(w, "  2  no decomposition of the given width exists, or the one checked is incorrect")
+	fmt.Fprintln(w, "  3  the search was stopped by the timeout or the memory limit")
+}
+
+// run selects the command given by the first argument, returning its exit code. For compatibility with earlier
+// versions, arguments starting with a flag are passed to the decompose command.
+func run(args []string) int {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return exitError
+	}
+
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		usage(os.Stdout)
+		return exitOK
+	}
+	if strings.HasPrefix(args[0], "-") {
+		return runDecompose(args)
+	}
+
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:])
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", args[0])
+	usage(os.Stderr)
+	return exitError
+}
+
+// parseFlags parses the flags of a command, printing its usage if asked to, or if the flags are invalid. The exit
+// code is returned if the command should stop.
+func parseFlags(flagSet *flag.FlagSet, args []string) (int, bool) {
+	flagSet.SetOutput(io.Discard)
+	err := flagSet.Parse(args)
+	flagSet.SetOutput(os.Stderr)
+
+	switch {
+	case err == flag.ErrHelp:
+		flagSet.SetOutput(os.Stdout)
+		flagSet.Usage()
+		return exitOK, true
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "Parse Error:", err)
+		flagSet.Usage()
+		return exitError, true
+	}
+
+	return exitOK, false
+}
+
+// printFlags prints the flags of the set accepted by the filter, together with their types and defaults
+func printFlags(w io.Writer, flagSet *flag.FlagSet, include func(name string) bool) {
+	flagSet.VisitAll(func(f *flag.Flag) {
+		if !include(f.Name) {
+			return
+		}
+
+		typeName, usage := flag.UnquoteUsage(f)
+		if typeName != "" {
+			fmt.Fprintf(w, "  -%-10s \t<%s>\n", f.Name, typeName)
+		} else {
+			fmt.Fprintf(w, "  -%-10s \n", f.Name)
+		}
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" && f.DefValue != "0s" {
+			usage += fmt.Sprintf(" (default %v)", f.DefValue)
+		}
+		fmt.Fprintln(w, "\t"+usage)
+	})
+}
+
+// activeFlags returns the flags given with a value other than their default
+func activeFlags(flagSet *flag.FlagSet) map[string]bool {
+	output := make(map[string]bool)
+
+	flagSet.Visit(func(f *flag.Flag) {
+		if f.Value.String() != f.DefValue {
+			output[f.Name] = true
+		}
+	})
+
+	return output
+}
@@ -0,0 +1,100 @@
+package main
+
+// Splitting hypergraphs into their connected components, which are decomposed independently and concurrently
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+// componentResult holds the outcome of the search for a decomposition of a single component
+type componentResult struct {
+	index     int
+	decomp    Decomp
+	width     int
+	algorithm string
+	times     []labelTime
+	err       error
+}
+
+// searchComponents searches for decompositions of the connected components of the graph concurrently, each with
+// its own search and hinge tree, and joins them into one. In exact mode, each component gets its own width. The
+// width of each component is reported, as well as the largest one, which is returned.
+func searchComponents(graph Graph, comps []Graph, width int, ctx runContext, opts options,
+	weights logk.Weights) (Decomp, int, string, []labelTime, error) {
+	start := time.Now()
+	ch := make(chan componentResult)
+
+	for i := range comps {
+		go func(i int) {
+			out := componentResult{index: i}
+
+			var hinget *lib.Hingetree
+			if opts.hinge {
+				tree := lib.GetHingeTree(comps[i])
+				hinget = &tree
+			}
+
+			out.decomp, out.width, out.algorithm, out.times, out.err = solve(comps[i], hinget, width, opts, weights)
+			ch <- out
+		}(i)
+	}
+
+	results := make([]componentResult, len(comps))
+	for range comps {
+		out := <-ch
+		results[out.index] = out
+	}
+
+	msec := time.Since(start).Seconds() * float64(time.Second/time.Millisecond)
+	times := []labelTime{{time: msec, label: "Decomposition"}}
+
+	fmt.Println("Split into", len(comps), "connected components")
+
+	var decomps []Decomp
+	maxWidth := 0
+	failed := false
+	for i, out := range results {
+		if out.err != nil {
+			return Decomp{}, width, "", times, out.err
+		}
+
+		var names []string
+		for _, e := range comps[i].Edges.Slice() {
+			names = append(names, ctx.encoding.Name(e.Name))
+		}
+
+		var sumTotal float64
+		for _, time := range out.times {
+			sumTotal = sumTotal + time.time
+		}
+
+		if reflect.DeepEqual(out.decomp, Decomp{}) {
+			fmt.Printf("Component %d (%s): no decomposition, %.5f ms\n", i+1, strings.Join(names, ", "), sumTotal)
+			failed = true
+			continue
+		}
+		fmt.Printf("Component %d (%s): width %d, %.5f ms\n", i+1, strings.Join(names, ", "),
+			out.decomp.CheckWidth(), sumTotal)
+
+		decomps = append(decomps, out.decomp)
+		if out.width > maxWidth {
+			maxWidth = out.width
+		}
+	}
+	fmt.Println()
+
+	if !opts.exact {
+		maxWidth = width
+	}
+	if failed {
+		return Decomp{}, maxWidth, results[0].algorithm, times, nil
+	}
+
+	return logk.JoinDecomps(graph, decomps), maxWidth, results[0].algorithm, times, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+// The convert command, translating hypergraphs between the supported formats
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+// runConvert implements the convert command, with the arguments following it
+func runConvert(args []string) int {
+	names := strings.Join(logk.FormatNames(), ", ")
+
+	flagSet := flag.NewFlagSet("convert", flag.ContinueOnError)
+	graphPath := flagSet.String("graph", "-", "input, or - to read from stdin")
+	from := flagSet.String("from", "hyperbench", "format of the input, out of "+names)
+	to := flagSet.String("to", "hyperbench", "format of the output, out of "+names)
+	outPath := flagSet.String("out", "-", "output file, or - to write to stdout")
+	namesPath := flagSet.String("names", "", "write the numbers of vertices and edges in the numeric formats,\n\t"+
+		"together with their names in the input, into the specified file")
+	sortFlag := flagSet.Bool("sort", false, "order the edges by name, and the vertices of each edge")
+	renumber := flagSet.Bool("renumber", false, "rename edges to E1, E2, ... and vertices to V1, V2, ... in order of appearance")
+
+	flagSet.Usage = func() {
+		fmt.Fprintln(flagSet.Output(), "Usage of log-k-decomp convert: -from <format> -to <format> [flags]")
+		flagSet.PrintDefaults()
+		fmt.Fprintln(flagSet.Output(), "\nFormats:")
+		for _, name := range logk.FormatNames() {
+			f, _ := logk.GetFormat(name)
+			fmt.Fprintf(flagSet.Output(), "  %-10s %s\n", f.Name, f.Description)
+		}
+	}
+	if code, stop := parseFlags(flagSet, args); stop {
+		return code
+	}
+
+	input, err := logk.GetFormat(*from)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	output, err := logk.GetFormat(*to)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	dat, err := logk.ReadInput(*graphPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	documents := []string{string(dat)}
+	if input.Split != nil {
+		documents = input.Split(string(dat))
+	}
+
+	out := os.Stdout
+	if *outPath != "-" {
+		if out, err = os.Create(*outPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		defer out.Close()
+	}
+	writer := bufio.NewWriter(out)
+
+	var nameMap []string
+	for i := range documents {
+		graph, encoding, err := input.Read(documents[i])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't read document %d of %d: %v\n", i+1, len(documents), err)
+			return exitError
+		}
+
+		if *sortFlag {
+			graph = logk.SortGraph(graph, encoding)
+		}
+		original := encoding
+		if *renumber {
+			encoding = logk.Renumber(graph)
+			if *sortFlag { // the new names of the vertices come in a different order
+				graph = logk.SortGraph(graph, encoding)
+			}
+		}
+
+		if err := output.Write(writer, graph, encoding); err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't write document %d of %d: %v\n", i+1, len(documents), err)
+			return exitError
+		}
+
+		if len(documents) > 1 {
+			nameMap = append(nameMap, fmt.Sprintf("document %d", i+1))
+		}
+		nameMap = append(nameMap, logk.NameMap(graph, encoding, original)...)
+	}
+
+	if err := writer.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, "Couldn't write output:", err)
+		return exitError
+	}
+	if out != os.Stdout {
+		if err := out.Close(); err != nil {
+			fmt.Fprintln(os.Stderr, "Couldn't write output:", err)
+			return exitError
+		}
+	}
+
+	if *namesPath != "" {
+		if err := os.WriteFile(*namesPath, []byte(strings.Join(nameMap, "\n")+"\n"), 0644); err != nil {
+			fmt.Fprintln(os.Stderr, "Couldn't write name map:", err)
+			return exitError
+		}
+	}
+
+	return exitOK
+}
@@ -0,0 +1,74 @@
+package main
+
+// The generate command, producing seeded random and structured hypergraphs as stress instances
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+// generatorTypes lists the kinds of hypergraphs the generate command can produce
+var generatorTypes = []string{"random", "grid", "cycle", "clique", "ktree", "query"}
+
+// runGenerate implements the generate command, with the arguments following it
+func runGenerate(args []string) int {
+	flagSet := flag.NewFlagSet("generate", flag.ContinueOnError)
+	kind := flagSet.String("type", "random", "kind of hypergraph, out of "+strings.Join(generatorTypes, ", "))
+	seed := flagSet.Int64("seed", 1, "seed for the random generators, the same seed always produces the same output")
+	count := flagSet.Int("count", 1, "number of hypergraphs to produce, written one after another")
+	n := flagSet.Int("n", 10, "number of vertices (random, cycle, clique, ktree) or variables (query)")
+	m := flagSet.Int("m", 10, "number of edges (random) or atoms (query)")
+	k := flagSet.Int("k", 2, "treewidth of the ktree")
+	rows := flagSet.Int("rows", 3, "number of rows of the grid")
+	cols := flagSet.Int("cols", 3, "number of columns of the grid")
+	arity := flagSet.Int("arity", 3, "largest number of vertices of an edge (random, query)")
+	pace := flagSet.Bool("pace", false, "write the output in PACE 2019 format, instead of HyperBench format")
+
+	flagSet.Usage = func() {
+		fmt.Fprintln(flagSet.Output(), "Usage of log-k-decomp generate: [flags]")
+		flagSet.PrintDefaults()
+	}
+	if code, stop := parseFlags(flagSet, args); stop {
+		return code
+	}
+	r := rand.New(rand.NewSource(*seed))
+
+	for i := 0; i < *count; i++ {
+		var g logk.Generated
+		var err error
+
+		switch *kind {
+		case "random":
+			g, err = logk.RandomHypergraph(r, *n, *m, *arity)
+		case "grid":
+			g, err = logk.Grid(*rows, *cols)
+		case "cycle":
+			g, err = logk.Cycle(*n)
+		case "clique":
+			g, err = logk.Clique(*n)
+		case "ktree":
+			g, err = logk.KTree(r, *n, *k)
+		case "query":
+			g, err = logk.RandomQuery(r, *m, *arity, *n)
+		default:
+			err = fmt.Errorf("unknown type %v, choose one of %v", *kind, strings.Join(generatorTypes, ", "))
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+
+		if *pace {
+			fmt.Print(g.PACE())
+		} else {
+			fmt.Print(g.HyperBench())
+		}
+	}
+
+	return exitOK
+}
@@ -1,8 +1,17 @@
 module github.com/cem-okulmus/log-k-decomp
 
-go 1.14
+go 1.18
 
 require (
 	github.com/cem-okulmus/BalancedGo v1.7.0
 	github.com/cem-okulmus/disjoint v1.1.2
+	github.com/klauspost/compress v1.15.15
+)
+
+require (
+	github.com/alecthomas/participle v0.3.0 // indirect
+	github.com/google/go-cmp v0.3.1 // indirect
+	github.com/json-iterator/go v1.1.12 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180228061459-e0a39a4cb421 // indirect
+	github.com/modern-go/reflect2 v1.0.2 // indirect
 )
@@ -1,4 +1,4 @@
-package main
+package lib
 
 // Parallel Algorithm for computing HD with log-depth recursion depth
 
@@ -6,20 +6,30 @@ import (
 	"fmt"
 	"log"
 	"reflect"
-	"runtime"
 
 	"github.com/cem-okulmus/BalancedGo/lib"
 	"github.com/cem-okulmus/disjoint"
-	logk "github.com/cem-okulmus/log-k-decomp/lib"
 )
 
 // LogKDecomp implements a parallel log-depth HD algorithm
 type LogKDecomp struct {
 	Graph     lib.Graph
 	K         int
-	cache     lib.Cache
+	cache     countingCache
+	counter   searchCounter
 	BalFactor int
 	Generator lib.SearchGenerator
+	Weights   Weights   // if set, cheaper separators are tried first
+	CostBound float64   // if positive, the maximal cost of any node
+	Stop      *StopFlag // if set, the search gives up once it is stopped
+	Balance   Balance   // if set, replaces BalFactor in deciding which separators are balanced
+	// TreeDecomp searches for a tree decomposition with bags of size at most K, covering bags by single vertices
+	TreeDecomp bool
+	// Memory, if set, empties the cache and hands subproblems over to DetKDecomp when memory runs low, and stops the
+	// search once the limit is exceeded
+	Memory *MemoryGuard
+	// Workers, if positive, is the number of workers searching for separators concurrently, instead of one per CPU
+	Workers int
 }
 
 // decompInt is used to keep track of returned decompositions during concurrent search
@@ -40,14 +50,40 @@ func (l *LogKDecomp) SetWidth(K int) {
 	l.K = K
 }
 
+// SetCost sets the weights of the edges and the bound on the cost of nodes
+func (l *LogKDecomp) SetCost(weights Weights, bound float64) {
+	l.cache.Reset() // the bound might invalidate any old results
+
+	l.Weights = weights
+	l.CostBound = bound
+}
+
 // Name returns the name of the algorithm
 func (l *LogKDecomp) Name() string {
+	if l.TreeDecomp {
+		return "LogKDecomp (tree decompositions)"
+	}
 	return "LogKDecomp"
 }
 
+// CacheStats reports how the searches of the algorithm used its cache
+func (l *LogKDecomp) CacheStats() CacheStats {
+	return l.cache.Stats()
+}
+
+// SearchStats reports the goroutines started by the searches of the algorithm, and the subgraphs handed over to
+// DetKDecomp
+func (l *LogKDecomp) SearchStats() SearchStats {
+	return l.counter.Stats()
+}
+
 // FindDecomp finds a decomp
 func (l *LogKDecomp) FindDecomp() lib.Decomp {
 	l.cache.Init()
+	l.counter.Init()
+	if l.TreeDecomp {
+		return l.findDecomp(l.Graph, []int{}, VertexEdges(l.Graph.Vertices()))
+	}
 	return l.findDecomp(l.Graph, []int{}, l.Graph.Edges)
 }
 
@@ -57,8 +93,31 @@ func (l *LogKDecomp) FindDecompGraph(Graph lib.Graph) lib.Decomp {
 	return l.FindDecomp()
 }
 
+// stopped checks if the search should give up, as it was stopped or the memory limit exceeded
+func (l *LogKDecomp) stopped() bool {
+	return l.Stop.Stopped() || l.Memory.Exceeded()
+}
+
+// detKFallback decomposes a subproblem with the sequential DetKDecomp, which needs less memory, sharing the cache
+func (l *LogKDecomp) detKFallback(H lib.Graph, Conn []int, allowed lib.Edges) lib.Decomp {
+	det := DetKDecomp{K: l.K, Graph: lib.Graph{Edges: allowed}, BalFactor: l.BalFactor, Weights: l.Weights,
+		CostBound: l.CostBound, Stop: l.Stop, Memory: l.Memory}
+
+	l.cache.CopyRef(&det.cache)
+	l.counter.Share(&det.counter)
+	l.counter.DetKCall()
+	return det.findDecomp(H, Conn, 0, det.Stop)
+}
+
 // determine whether we have reached a (positive or negative) base case
-func (l *LogKDecomp) baseCaseCheck(lenE int, lenSp int, lenAE int) bool {
+func (l *LogKDecomp) baseCaseCheck(H lib.Graph, lenAE int) bool {
+	lenE := H.Edges.Len()
+	lenSp := len(H.Special)
+
+	if l.TreeDecomp {
+		return len(H.Vertices()) <= l.K || lenE == 0 || lenAE == 0
+	}
+
 	if lenE <= l.K && lenSp == 0 {
 		return true
 	}
@@ -79,6 +138,10 @@ func (l *LogKDecomp) baseCase(H lib.Graph, lenAE int) lib.Decomp {
 	// log.Printf("Base case reached. Number of Special Edges %d\n", len(Sp))
 	var output lib.Decomp
 
+	if l.TreeDecomp {
+		return l.baseCaseTD(H)
+	}
+
 	// cover faiure cases
 	if H.Edges.Len() == 0 && len(H.Special) > 1 {
 		return lib.Decomp{}
@@ -88,7 +151,7 @@ func (l *LogKDecomp) baseCase(H lib.Graph, lenAE int) lib.Decomp {
 	}
 
 	// construct a decomp in the remaining two
-	if H.Edges.Len() <= l.K && len(H.Special) == 0 {
+	if H.Edges.Len() <= l.K && len(H.Special) == 0 && l.Weights.Allows(H.Edges, l.CostBound) {
 		output = lib.Decomp{Graph: H, Root: lib.Node{Bag: H.Vertices(), Cover: H.Edges}}
 	}
 	if H.Edges.Len() == 0 && len(H.Special) == 1 {
@@ -101,6 +164,27 @@ func (l *LogKDecomp) baseCase(H lib.Graph, lenAE int) lib.Decomp {
 	return output
 }
 
+// base case for tree decompositions, where a single node suffices once there are at most K vertices left. Any
+// special edges become leaves below it, to connect the subtrees later.
+func (l *LogKDecomp) baseCaseTD(H lib.Graph) lib.Decomp {
+	if H.Edges.Len() == 0 && len(H.Special) == 1 {
+		sp1 := H.Special[0]
+		return lib.Decomp{Graph: H, Root: lib.Node{Bag: sp1.Vertices(), Cover: sp1}}
+	}
+
+	vertices := H.Vertices()
+	if len(vertices) > l.K {
+		return lib.Decomp{}
+	}
+
+	root := lib.Node{Bag: vertices, Cover: VertexEdges(vertices)}
+	for _, sp := range H.Special {
+		root.Children = append(root.Children, lib.Node{Bag: sp.Vertices(), Cover: sp})
+	}
+
+	return lib.Decomp{Graph: H, Root: root}
+}
+
 //attach the two subtrees to form one
 func attachingSubtrees(subtreeAbove lib.Node, subtreeBelow lib.Node, connecting lib.Edges) lib.Node {
 	// log.Println("Two Nodes enter: ", subtreeAbove, subtreeBelow)
@@ -119,6 +203,62 @@ func attachingSubtrees(subtreeAbove lib.Node, subtreeBelow lib.Node, connecting
 	return *leaf
 }
 
+// childSearch sets up the search for balanced separators of H among the allowed edges
+func (l *LogKDecomp) childSearch(H *lib.Graph, allowed *lib.Edges) (lib.Search, lib.Predicate) {
+	genChild := lib.SplitCombin(allowed.Len(), l.K, l.Memory.Workers(l.Workers), false)
+	search := l.Generator.GetSearch(H, allowed, l.BalFactor, genChild)
+	pred := l.Weights.WithCost(BalancedCheck{Balance: l.Balance}, l.CostBound)
+	search.FindNext(pred) // initial Search
+
+	return search, pred
+}
+
+// parentSearch sets up the search for parents of the child among the allowed edges, whose low component
+// contains the child
+func (l *LogKDecomp) parentSearch(H *lib.Graph, allowedParent *lib.Edges, Conn []int,
+	childλ lib.Edges) (lib.Search, lib.Predicate) {
+	genParent := lib.SplitCombin(allowedParent.Len(), l.K, l.Memory.Workers(l.Workers), false)
+	search := l.Generator.GetSearch(H, allowedParent, l.BalFactor, genParent)
+	pred := l.Weights.WithCost(ParentCheck{Conn: Conn, Child: childλ.Vertices(), Balance: l.Balance},
+		l.CostBound)
+	search.FindNext(pred)
+
+	return search, pred
+}
+
+// lowComponent finds the component of the parent which is too large for it to be balanced, returning false if
+// there is none
+func (l *LogKDecomp) lowComponent(H lib.Graph, compsπ []lib.Graph, parentλ lib.Edges) (int, bool) {
+	balance := l.Balance.OrFactor(l.BalFactor)
+
+	compLowIndex, foundLow := 0, false
+	for i := range compsπ {
+		if balance.TooLarge(H, compsπ[i], parentλ) {
+			foundLow = true
+			compLowIndex = i //keep track of the index for composing comp_up later
+		}
+	}
+
+	return compLowIndex, foundLow
+}
+
+// upperComponent collects the edges and special edges of the components of the parent other than the low one,
+// which make up the upper component
+func upperComponent(compsπ []lib.Graph, compLowIndex int, isolatedEdges []lib.Edge) ([]lib.Edge, []lib.Edges) {
+	tempEdgeSlice := []lib.Edge{}
+	tempSpecialSlice := []lib.Edges{}
+
+	tempEdgeSlice = append(tempEdgeSlice, isolatedEdges...)
+	for i := range compsπ {
+		if i != compLowIndex {
+			tempEdgeSlice = append(tempEdgeSlice, compsπ[i].Edges.Slice()...)
+			tempSpecialSlice = append(tempSpecialSlice, compsπ[i].Special...)
+		}
+	}
+
+	return tempEdgeSlice, tempSpecialSlice
+}
+
 func (l *LogKDecomp) findDecomp(H lib.Graph, Conn []int, allowedFull lib.Edges) lib.Decomp {
 
 	// log.Printf("\n\nCurrent SubGraph: %v\n", H)
@@ -128,28 +268,30 @@ func (l *LogKDecomp) findDecomp(H lib.Graph, Conn []int, allowedFull lib.Edges)
 	if !lib.Subset(Conn, H.Vertices()) {
 		log.Panicln("Conn invariant violated.")
 	}
+	if l.stopped() {
+		return lib.Decomp{}
+	}
+	l.cache.Trim(l.Memory)
 
 	// Base Case
-	if l.baseCaseCheck(H.Edges.Len(), len(H.Special), allowedFull.Len()) {
+	if l.baseCaseCheck(H, allowedFull.Len()) {
 		return l.baseCase(H, allowedFull.Len())
 	}
+	if l.Memory.High() && !l.TreeDecomp {
+		return l.detKFallback(H, Conn, allowedFull)
+	}
 	//all vertices within (H ∪ Sp)
-	VerticesH := append(H.Vertices())
+	VerticesH := H.Vertices()
 
-	allowed := lib.FilterVertices(allowedFull, VerticesH)
+	allowed := l.Weights.Sort(lib.FilterVertices(allowedFull, VerticesH))
 
 	// Set up iterator for child
-
-	genChild := lib.SplitCombin(allowed.Len(), l.K, runtime.GOMAXPROCS(-1), false)
-	parallelSearch := l.Generator.GetSearch(&H, &allowed, l.BalFactor, genChild)
-	// parallelSearch := lib.Search{H: &H, Edges: &allowed, BalFactor: l.BalFactor, Generators: genChild}
-	pred := lib.BalancedCheck{}
-	parallelSearch.FindNext(pred) // initial Search
+	parallelSearch, pred := l.childSearch(&H, &allowed)
 	var Vertices = make(map[int]*disjoint.Element)
 
 	// checks all possibles nodes in H, together with PARENT loops, it covers all parent-child pairings
 CHILD:
-	for ; !parallelSearch.SearchEnded(); parallelSearch.FindNext(pred) {
+	for ; !parallelSearch.SearchEnded() && !l.stopped(); parallelSearch.FindNext(pred) {
 
 		childλ := lib.GetSubset(allowed, parallelSearch.GetResult())
 		compsε, _, _ := H.GetComponents(childλ, Vertices)
@@ -194,34 +336,18 @@ CHILD:
 
 		// Set up iterator for parent
 		allowedParent := lib.FilterVertices(allowed, append(Conn, childλ.Vertices()...))
-		genParent := lib.SplitCombin(allowedParent.Len(), l.K, runtime.GOMAXPROCS(-1), false)
-		parentalSearch := l.Generator.GetSearch(&H, &allowedParent, l.BalFactor, genParent)
-		// parentalSearch := lib.Search{H: &H, Edges: &allowedParent, BalFactor: l.BalFactor, Generators: genParent}
-		predPar := logk.ParentCheck{Conn: Conn, Child: childλ.Vertices()}
-		parentalSearch.FindNext(predPar)
+		parentalSearch, predPar := l.parentSearch(&H, &allowedParent, Conn, childλ)
 		// parentFound := false
 	PARENT:
-		for ; !parentalSearch.SearchEnded(); parentalSearch.FindNext(predPar) {
+		for ; !parentalSearch.SearchEnded() && !l.stopped(); parentalSearch.FindNext(predPar) {
 
 			parentλ := lib.GetSubset(allowedParent, parentalSearch.GetResult())
 			// log.Println("Looking at parent ", parentλ)
 			compsπ, _, isolatedEdges := H.GetComponents(parentλ, Vertices)
 			// log.Println("Parent components ", comps_p)
 
-			foundLow := false
-			var compLowIndex int
-			var compLow lib.Graph
-
-			balancednessLimit := (((H.Len()) * (l.BalFactor - 1)) / l.BalFactor)
-
 			// Check if parent is un-balanced
-			for i := range compsπ {
-				if compsπ[i].Len() > balancednessLimit {
-					foundLow = true
-					compLowIndex = i //keep track of the index for composing comp_up later
-					compLow = compsπ[i]
-				}
-			}
+			compLowIndex, foundLow := l.lowComponent(H, compsπ, parentλ)
 			if !foundLow {
 				fmt.Println("Current SubGraph, ", H)
 				fmt.Println("Conn ", lib.PrintVertices(Conn))
@@ -242,6 +368,7 @@ CHILD:
 				log.Panicln("the parallel search didn't actually find a valid parent")
 			}
 
+			compLow := compsπ[compLowIndex]
 			vertCompLow := compLow.Vertices()
 			childχ := lib.Inter(childλ.Vertices(), vertCompLow)
 
@@ -274,16 +401,7 @@ CHILD:
 			var compUp lib.Graph
 			var decompUp lib.Decomp
 			var specialChild lib.Edges
-			tempEdgeSlice := []lib.Edge{}
-			tempSpecialSlice := []lib.Edges{}
-
-			tempEdgeSlice = append(tempEdgeSlice, isolatedEdges...)
-			for i := range compsπ {
-				if i != compLowIndex {
-					tempEdgeSlice = append(tempEdgeSlice, compsπ[i].Edges.Slice()...)
-					tempSpecialSlice = append(tempSpecialSlice, compsπ[i].Special...)
-				}
-			}
+			tempEdgeSlice, tempSpecialSlice := upperComponent(compsπ, compLowIndex, isolatedEdges)
 
 			// specialChild = NewEdges([]Edge{Edge{Vertices: Inter(childχ, comp_up.Vertices())}})
 			specialChild = lib.NewEdges([]lib.Edge{{Vertices: childχ}})
@@ -298,6 +416,7 @@ CHILD:
 				decompTemp := lib.Decomp{Graph: compUp, Root: lib.Node{Bag: lib.Inter(parentλ.Vertices(), VerticesH),
 					Cover: parentλ, Children: []lib.Node{{Bag: specialChild.Vertices(), Cover: childλ}}}}
 
+				l.counter.Goroutine()
 				go func(decomp lib.Decomp) {
 					chUp <- decomp
 				}(decompTemp)
@@ -315,6 +434,7 @@ CHILD:
 				//Reducing the allowed edges
 				allowedReduced := allowedFull.Diff(compLow.Edges)
 
+				l.counter.Goroutine()
 				go func(comp_up lib.Graph, Conn []int, allowedReduced lib.Edges) {
 					chUp <- l.findDecomp(comp_up, Conn, allowedReduced)
 				}(compUp, Conn, allowedReduced)
@@ -329,6 +449,7 @@ CHILD:
 			for x := range compsε {
 				Connχ := lib.Inter(compsε[x].Vertices(), childχ)
 
+				l.counter.Goroutine()
 				go func(x int, comps_c []lib.Graph, Conn_x []int, allowedFull lib.Edges) {
 					var out decompInt
 					out.Decomp = l.findDecomp(comps_c[x], Conn_x, allowedFull)
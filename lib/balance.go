@@ -0,0 +1,152 @@
+package lib
+
+// balance.go decides when separators are balanced, allowing rational ratios and measuring the size of components
+// by their edges, their vertices or the weights of their edges
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	"github.com/cem-okulmus/disjoint"
+)
+
+// A Measure determines how the size of components is measured when checking for balancedness
+type Measure int
+
+// The measures available for balancedness
+const (
+	EdgeMeasure   Measure = iota // the number of edges and special edges
+	VertexMeasure                // the number of vertices not in the separator
+	WeightMeasure                // the summed weights of the edges, with special edges counting as 1
+)
+
+// Measures lists the names of the measures, as used by ParseMeasure
+var Measures = []string{"edges", "vertices", "weights"}
+
+// ParseMeasure reads the name of a measure
+func ParseMeasure(name string) (Measure, error) {
+	for i := range Measures {
+		if strings.ToLower(strings.TrimSpace(name)) == Measures[i] {
+			return Measure(i), nil
+		}
+	}
+
+	return EdgeMeasure, fmt.Errorf("unknown measure %q, expected one of %v", name, strings.Join(Measures, ", "))
+}
+
+// Balance determines when a separator is balanced: no component may be larger than the ratio Num/Den of the whole
+// hypergraph. The zero value isn't valid, and is replaced by the integer balance factor passed to the checks.
+type Balance struct {
+	Num     int
+	Den     int
+	Measure Measure
+	Weights Weights // used by WeightMeasure
+}
+
+// NewBalance returns the balance used by the integer balance factor f, i.e. the ratio (f-1)/f measured in edges
+func NewBalance(f int) Balance {
+	return Balance{Num: f - 1, Den: f}
+}
+
+// ParseBalance reads a ratio such as "3/5", or an integer balance factor such as "2", which stands for (2-1)/2. For
+// balanced separators to exist, the ratio needs to be at least 1/2, and it must be smaller than 1 to make progress.
+func ParseBalance(ratio string) (Balance, error) {
+	var b Balance
+	var err error
+
+	if parts := strings.Split(ratio, "/"); len(parts) == 2 {
+		b.Num, err = strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err == nil {
+			b.Den, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+		}
+	} else {
+		var f int
+		f, err = strconv.Atoi(strings.TrimSpace(ratio))
+		b = NewBalance(f)
+	}
+
+	if err != nil {
+		return b, fmt.Errorf("invalid balance %q, expected a ratio such as 3/5 or an integer factor", ratio)
+	}
+	if b.Den <= 0 || 2*b.Num < b.Den || b.Num >= b.Den {
+		return b, fmt.Errorf("invalid balance %q, the ratio has to be at least 1/2 and smaller than 1", ratio)
+	}
+
+	return b, nil
+}
+
+// String returns the ratio and the measure of the balance
+func (b Balance) String() string {
+	return fmt.Sprintf("%d/%d of %s", b.Num, b.Den, Measures[b.Measure])
+}
+
+// OrFactor replaces the zero value with the balance of the integer balance factor
+func (b Balance) OrFactor(balFactor int) Balance {
+	if b.Den == 0 {
+		return NewBalance(balFactor)
+	}
+	return b
+}
+
+// size measures the graph, not counting the vertices of the separator
+func (b Balance) size(g lib.Graph, sep lib.Edges) float64 {
+	switch b.Measure {
+	case VertexMeasure:
+		return float64(len(lib.Diff(g.Vertices(), sep.Vertices())))
+	case WeightMeasure:
+		output := float64(len(g.Special))
+		for _, e := range g.Edges.Slice() {
+			output = output + b.Weights.Weight(e)
+		}
+		return output
+	}
+
+	return float64(g.Len())
+}
+
+// TooLarge checks if the component of H, produced by the separator, is too large for the separator to be balanced.
+// A component with as many edges as H never makes progress, and is thus always too large.
+func (b Balance) TooLarge(H lib.Graph, comp lib.Graph, sep lib.Edges) bool {
+	if comp.Len() >= H.Len() {
+		return true
+	}
+
+	var total float64
+	if b.Measure == VertexMeasure {
+		total = float64(len(H.Vertices()))
+	} else {
+		total = b.size(H, sep)
+	}
+
+	return b.size(comp, sep)*float64(b.Den) > total*float64(b.Num)
+}
+
+// BalancedCheck looks for balanced separators, like the one of BalancedGo, but using a Balance
+type BalancedCheck struct {
+	Balance Balance // if not set, the balance factor of the search is used
+}
+
+// Check performs the needed computation to ensure whether sep is a balanced separator
+func (c BalancedCheck) Check(H *lib.Graph, sep *lib.Edges, balFactor int, Vertices map[int]*disjoint.Element) bool {
+	balance := c.Balance.OrFactor(balFactor)
+
+	//balancedness condition
+	comps, _, _ := H.GetComponents(*sep, Vertices)
+
+	for i := range comps {
+		if balance.TooLarge(*H, comps[i], *sep) {
+			return false
+		}
+	}
+
+	// Make sure that "special seps can never be used as separators"
+	for i := range H.Special {
+		if lib.IntHash(H.Special[i].Vertices()) == lib.IntHash(sep.Vertices()) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,86 @@
+package lib
+
+// cachestats.go extends the cache of BalancedGo by counting how it is used, to compare changes to the algorithms
+
+import (
+	"sync/atomic"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+)
+
+// CacheStats reports how an algorithm used its cache, over all searches since it was created
+type CacheStats struct {
+	Checks    int64 // lookups of separators
+	Hits      int64 // lookups showing that a separator fails, so it can be skipped
+	Added     int64 // separators added as failing
+	Evictions int64 // times the cache was emptied to save memory
+	Entries   int   // separators currently holding entries
+}
+
+// countingCache is a cache counting its lookups and additions. Caches copied by reference share their counters.
+type countingCache struct {
+	lib.Cache
+	stats   *CacheStats
+	trimmed int64 // the last request of a memory guard to empty the cache which was followed
+}
+
+// Init needs to be called to initialise the cache
+func (c *countingCache) Init() {
+	c.Cache.Init()
+	if c.stats == nil {
+		c.stats = &CacheStats{}
+	}
+}
+
+// CopyRef allows for safe copying of a cache by reference, together with its counters
+func (c *countingCache) CopyRef(other *countingCache) {
+	c.Init()
+	c.Cache.CopyRef(&other.Cache)
+	other.stats = c.stats
+	atomic.StoreInt64(&other.trimmed, atomic.LoadInt64(&c.trimmed))
+}
+
+// Trim empties the cache if the memory guard asked for it since the last time. Copies of the cache hold on to its
+// entries until they are trimmed themselves.
+func (c *countingCache) Trim(m *MemoryGuard) {
+	requested := m.Evictions()
+	trimmed := atomic.LoadInt64(&c.trimmed)
+
+	if trimmed < requested && atomic.CompareAndSwapInt64(&c.trimmed, trimmed, requested) {
+		c.Cache.Reset()
+		atomic.AddInt64(&c.stats.Evictions, 1)
+	}
+}
+
+// CheckNegative checks if the separator is known to fail for some of the components
+func (c *countingCache) CheckNegative(sep lib.Edges, comps []lib.Graph) bool {
+	hit := c.Cache.CheckNegative(sep, comps)
+
+	atomic.AddInt64(&c.stats.Checks, 1)
+	if hit {
+		atomic.AddInt64(&c.stats.Hits, 1)
+	}
+
+	return hit
+}
+
+// AddNegative records that the separator fails for the component
+func (c *countingCache) AddNegative(sep lib.Edges, comp lib.Graph) {
+	c.Cache.AddNegative(sep, comp)
+	atomic.AddInt64(&c.stats.Added, 1)
+}
+
+// Stats returns the counters, together with the current number of entries
+func (c *countingCache) Stats() CacheStats {
+	if c.stats == nil {
+		return CacheStats{} // never used
+	}
+
+	return CacheStats{
+		Checks:    atomic.LoadInt64(&c.stats.Checks),
+		Hits:      atomic.LoadInt64(&c.stats.Hits),
+		Added:     atomic.LoadInt64(&c.stats.Added),
+		Evictions: atomic.LoadInt64(&c.stats.Evictions),
+		Entries:   c.Cache.Len(),
+	}
+}
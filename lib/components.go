@@ -0,0 +1,80 @@
+package lib
+
+// components.go splits hypergraphs into their connected components, and joins the decompositions of the components
+
+import (
+	"github.com/cem-okulmus/BalancedGo/lib"
+)
+
+// ConnectedComponents splits the hypergraph into its connected components, ordered by the first edge of each. Edges
+// without vertices are covered by any bag, and are put into the first component.
+func ConnectedComponents(g lib.Graph) []lib.Graph {
+	parent := make(map[int]int)
+	var find func(v int) int
+	find = func(v int) int {
+		if parent[v] != v {
+			parent[v] = find(parent[v])
+		}
+		return parent[v]
+	}
+
+	for _, v := range g.Vertices() {
+		parent[v] = v
+	}
+	for _, e := range g.Edges.Slice() {
+		if len(e.Vertices) == 0 {
+			continue
+		}
+		for _, v := range e.Vertices[1:] {
+			parent[find(v)] = find(e.Vertices[0])
+		}
+	}
+
+	index := make(map[int]int) // position of the component of each representative
+	var edges [][]lib.Edge
+	var empty []lib.Edge
+	for _, e := range g.Edges.Slice() {
+		if len(e.Vertices) == 0 {
+			empty = append(empty, e)
+			continue
+		}
+		r := find(e.Vertices[0])
+		i, ok := index[r]
+		if !ok {
+			i = len(edges)
+			index[r] = i
+			edges = append(edges, nil)
+		}
+		edges[i] = append(edges[i], e)
+	}
+	if len(empty) > 0 {
+		if len(edges) == 0 {
+			edges = append(edges, nil)
+		}
+		edges[0] = append(edges[0], empty...)
+	}
+
+	var output []lib.Graph
+	for i := range edges {
+		output = append(output, lib.Graph{Edges: lib.NewEdges(edges[i])})
+	}
+
+	return output
+}
+
+// JoinDecomps joins the decompositions of the connected components of g into one, by attaching them below the
+// root of the first. As the components share no vertices, this keeps the decomposition valid, and its width is the
+// largest width of the parts.
+func JoinDecomps(g lib.Graph, decomps []lib.Decomp) lib.Decomp {
+	if len(decomps) == 0 {
+		return lib.Decomp{}
+	}
+
+	root := decomps[0].Root
+	root.Children = append([]lib.Node{}, root.Children...)
+	for _, d := range decomps[1:] {
+		root.Children = append(root.Children, d.Root)
+	}
+
+	return lib.Decomp{Graph: g, Root: root}
+}
@@ -0,0 +1,156 @@
+package lib
+
+// cost.go estimates the cost of evaluating a query along a hypertree decomposition, based on weights of the
+// edges, such as the cardinalities of their relations
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	"github.com/cem-okulmus/disjoint"
+)
+
+// Weights assign to each edge, identified by its name, an estimate of the size of its relation. Edges without a
+// weight, such as special edges, count as 1.
+type Weights map[int]float64
+
+// ReadWeights reads weights for the edges of g with one edge per line, given by its name followed by its weight,
+// e.g. "R1 1000". Empty lines and lines starting with "%" are ignored.
+func ReadWeights(r io.Reader, g lib.Graph, encoding Encoding) (Weights, error) {
+	output := make(Weights)
+	edges := make(map[int]bool)
+	for _, e := range g.Edges.Slice() {
+		edges[e.Name] = true
+	}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "%") {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected edge name and weight", line)
+		}
+
+		e, ok := encoding.ID(fields[0])
+		if !ok || !edges[e] {
+			return nil, fmt.Errorf("line %d: unknown edge %v", line, fields[0])
+		}
+		w, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("line %d: invalid weight %v", line, fields[1])
+		}
+
+		output[e] = w
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// WeightsFromDatabase uses the cardinalities of the relations of a database as weights
+func WeightsFromDatabase(db Database) Weights {
+	output := make(Weights)
+
+	for e, r := range db {
+		output[e] = float64(r.Len())
+	}
+
+	return output
+}
+
+// Weight returns the weight of a single edge
+func (w Weights) Weight(e lib.Edge) float64 {
+	if weight, ok := w[e.Name]; ok {
+		return weight
+	}
+	return 1
+}
+
+// Cost estimates the cost of a node with the given cover by the size of the join of its relations, bounded by the
+// product of their weights
+func (w Weights) Cost(cover lib.Edges) float64 {
+	output := 1.0
+
+	for _, e := range cover.Slice() {
+		output = output * w.Weight(e)
+	}
+
+	return output
+}
+
+// Allows checks if a cover stays within the cost bound. Without weights or with a non-positive bound, any cover
+// is allowed.
+func (w Weights) Allows(cover lib.Edges, bound float64) bool {
+	if w == nil || bound <= 0 {
+		return true
+	}
+	return w.Cost(cover) <= bound
+}
+
+// Sort orders edges by increasing weight, so that cheap separators are considered first. The order among edges of
+// the same weight is kept, and the input is not modified.
+func (w Weights) Sort(edges lib.Edges) lib.Edges {
+	if w == nil {
+		return edges
+	}
+
+	slice := append([]lib.Edge{}, edges.Slice()...)
+	sort.SliceStable(slice, func(i, j int) bool {
+		return w.Weight(slice[i]) < w.Weight(slice[j])
+	})
+
+	return lib.NewEdges(slice)
+}
+
+// Annotate stores the cost of each node of the decomposition in the node, and returns the total cost, as the sum
+// over all nodes, together with the cost of the most expensive node
+func (w Weights) Annotate(d *lib.Decomp) (total float64, max float64) {
+	var visit func(n *lib.Node)
+	visit = func(n *lib.Node) {
+		n.Cost = w.Cost(n.Cover)
+		total = total + n.Cost
+		if n.Cost > max {
+			max = n.Cost
+		}
+
+		for i := range n.Children {
+			visit(&n.Children[i])
+		}
+	}
+	visit(&d.Root)
+
+	return total, max
+}
+
+// CostCheck extends a predicate used in the search for separators, rejecting those exceeding the cost bound
+type CostCheck struct {
+	Pred    lib.Predicate
+	Weights Weights
+	Bound   float64
+}
+
+// Check performs the check of the extended predicate only for separators within the cost bound
+func (c CostCheck) Check(H *lib.Graph, sep *lib.Edges, balFactor int, Vertices map[int]*disjoint.Element) bool {
+	if !c.Weights.Allows(*sep, c.Bound) {
+		return false
+	}
+
+	return c.Pred.Check(H, sep, balFactor, Vertices)
+}
+
+// WithCost extends a predicate with the cost bound, if there is one
+func (w Weights) WithCost(pred lib.Predicate, bound float64) lib.Predicate {
+	if w == nil || bound <= 0 {
+		return pred
+	}
+	return CostCheck{Pred: pred, Weights: w, Bound: bound}
+}
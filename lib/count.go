@@ -0,0 +1,141 @@
+package lib
+
+// count.go implements counting the answers of a conjunctive query by dynamic programming over a hypertree
+// decomposition of its hypergraph, without materialising the answers themselves
+
+import (
+	"math/big"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+)
+
+// countTree computes the number of assignments consistent with all bag relations of the join tree, bottom-up over
+// the nodes in the slice alive
+func (t *joinTree) countTree(alive []bool) *big.Int {
+	counts := make([][]*big.Int, len(t.nodes))
+
+	for i := len(t.nodes) - 1; i >= 0; i-- { // reverse pre-order visits children before parents
+		if !alive[i] {
+			continue
+		}
+
+		counts[i] = make([]*big.Int, t.rels[i].Len())
+		for j := range counts[i] {
+			counts[i][j] = big.NewInt(1)
+		}
+
+		for _, c := range t.children[i] {
+			if !alive[c] {
+				continue
+			}
+
+			shared := lib.Inter(t.rels[i].Attributes, t.rels[c].Attributes)
+			posC := t.rels[c].positions(shared)
+			posP := t.rels[i].positions(shared)
+
+			// sum up the counts of the child, grouped by the values on the shared vertices
+			sums := make(map[string]*big.Int)
+			for j, tuple := range t.rels[c].Tuples {
+				k := key(tuple, posC)
+				if _, ok := sums[k]; !ok {
+					sums[k] = new(big.Int)
+				}
+				sums[k].Add(sums[k], counts[c][j])
+			}
+
+			for j, tuple := range t.rels[i].Tuples {
+				sum, ok := sums[key(tuple, posP)]
+				if !ok {
+					counts[i][j].SetInt64(0)
+					continue
+				}
+				counts[i][j].Mul(counts[i][j], sum)
+			}
+			counts[c] = nil // no longer needed
+		}
+	}
+
+	total := new(big.Int)
+	for _, c := range counts[0] {
+		total.Add(total, c)
+	}
+
+	return total
+}
+
+// Count computes the number of answers of the query described by the graph over the database. If free is empty,
+// all vertices are free, and the number of homomorphisms is counted.
+//
+// Otherwise, the answers projected to the free vertices are counted: after the full semi-join reduction,
+// subtrees without any further free vertices only act as filters and are dropped. If every remaining non-free
+// vertex then occurs in a single bag, it is projected away and the same dynamic programming is used. Only if this
+// is not the case are the projected answers enumerated, which still avoids computing the full result.
+func Count(d lib.Decomp, g lib.Graph, db Database, free []int) (*big.Int, error) {
+	t, err := bagRelations(d, g, db)
+	if err != nil {
+		return nil, err
+	}
+
+	alive := make([]bool, len(t.nodes))
+	for i := range alive {
+		alive[i] = true
+	}
+
+	if len(free) == 0 || lib.Subset(g.Vertices(), free) {
+		return t.countTree(alive), nil
+	}
+
+	t.reduceUp()
+	t.reduceDown()
+	if t.rels[0].Len() == 0 {
+		return new(big.Int), nil
+	}
+
+	// determine which subtrees introduce new free vertices
+	hasFree := make([]bool, len(t.nodes))
+	for i := len(t.nodes) - 1; i >= 0; i-- {
+		var local []int
+		if p := t.parent[i]; p >= 0 {
+			local = lib.Diff(t.nodes[i].Bag, t.nodes[p].Bag)
+		} else {
+			local = t.nodes[i].Bag
+		}
+		if len(lib.Inter(local, free)) > 0 {
+			hasFree[i] = true
+		}
+		for _, c := range t.children[i] {
+			hasFree[i] = hasFree[i] || hasFree[c]
+		}
+	}
+	for i := 1; i < len(t.nodes); i++ {
+		alive[i] = alive[t.parent[i]] && hasFree[i]
+	}
+
+	// check that no existential vertex is shared between remaining nodes
+	occurrences := make(map[int]int)
+	for i := range t.nodes {
+		if !alive[i] {
+			continue
+		}
+		for _, v := range lib.Diff(t.nodes[i].Bag, free) {
+			occurrences[v]++
+		}
+	}
+	for _, n := range occurrences {
+		if n > 1 {
+			answers, err := Evaluate(d, g, db, free)
+			if err != nil {
+				return nil, err
+			}
+			return big.NewInt(int64(answers.Len())), nil
+		}
+	}
+
+	for i := range t.nodes {
+		if alive[i] {
+			t.rels[i] = t.rels[i].Project(lib.Inter(t.nodes[i].Bag, free))
+		}
+	}
+
+	return t.countTree(alive), nil
+}
@@ -0,0 +1,499 @@
+package lib
+
+import (
+	"log"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	"github.com/cem-okulmus/disjoint"
+)
+
+// DetKDecomp computes for a graph and some width K a HD of width K if it exists
+type DetKDecomp struct {
+	K         int
+	Graph     lib.Graph
+	BalFactor int
+	SubEdge   bool
+	cache     countingCache
+	counter   searchCounter
+	pool      *workerPool // shared by all levels of the search, and by searches handing subgraphs over
+	Weights   Weights     // if set, cheaper separators are tried first
+	CostBound float64     // if positive, the maximal cost of any node
+	Stop      *StopFlag   // if set, the search gives up once it is stopped
+	Parallel  bool        // if set, covers and components are explored concurrently
+	// Memory, if set, empties the cache and makes the search sequential when memory runs low, and stops the search
+	// once the limit is exceeded
+	Memory *MemoryGuard
+	// Workers, if positive, is the number of workers searching for separators concurrently, instead of one per CPU
+	Workers int
+}
+
+// SetGenerator is only needed to implement the Algorithm interface, as DetKDecomp enumerates separators itself
+func (d *DetKDecomp) SetGenerator(Gen lib.SearchGenerator) {}
+
+// SetWidth sets the current width parameter of the algorithm
+func (d *DetKDecomp) SetWidth(K int) {
+	d.cache.Reset() // reset the cache as the new width might invalidate any old results
+
+	d.K = K
+}
+
+// SetCost sets the weights of the edges and the bound on the cost of nodes
+func (d *DetKDecomp) SetCost(weights Weights, bound float64) {
+	d.cache.Reset() // the bound might invalidate any old results
+
+	d.Weights = weights
+	d.CostBound = bound
+}
+
+// stopped checks if the search should give up, as the flag was stopped or the memory limit exceeded
+func (d *DetKDecomp) stopped(stop *StopFlag) bool {
+	return stop.Stopped() || d.Memory.Exceeded()
+}
+
+// parallel checks if covers and components should be explored concurrently, which is given up once memory runs low
+func (d *DetKDecomp) parallel() bool {
+	return d.Parallel && !d.Memory.High()
+}
+
+// helper reserves a goroutine of the pool for exploring covers or components concurrently, and returns false if
+// all of them are in use
+func (d *DetKDecomp) helper() bool {
+	return d.pool.TryAcquire(d.Memory.Workers(d.Workers))
+}
+
+func (d *DetKDecomp) findHD(currentGraph lib.Graph) lib.Decomp {
+	d.cache.Init()
+	d.counter.Init()
+	if d.pool == nil {
+		d.pool = &workerPool{}
+	}
+	return d.findDecomp(currentGraph, []int{}, 0, d.Stop)
+}
+
+// FindDecomp finds a decomp
+func (d *DetKDecomp) FindDecomp() lib.Decomp {
+	return d.findHD(d.Graph)
+}
+
+// Name returns the name of the algorithm
+func (d *DetKDecomp) Name() string {
+	if d.SubEdge {
+		return "DetK with local BIP"
+	}
+	return "DetK"
+}
+
+// CacheStats reports how the searches of the algorithm used its cache
+func (d *DetKDecomp) CacheStats() CacheStats {
+	return d.cache.Stats()
+}
+
+// SearchStats reports the goroutines started by the searches of the algorithm, and the subgraphs handed over to
+// DetKDecomp
+func (d *DetKDecomp) SearchStats() SearchStats {
+	return d.counter.Stats()
+}
+
+// FindDecompGraph finds a decomp, for an explicit graph
+func (d *DetKDecomp) FindDecompGraph(G lib.Graph) lib.Decomp {
+	d.Graph = G
+	return d.findHD(G)
+}
+
+func connectingSep(sep []int, conn []int, comp []int) bool {
+	if !lib.Subset(conn, sep) {
+		return false
+	}
+
+	if len(lib.Inter(sep, comp)) == 0 {
+		return false
+	}
+
+	return true
+}
+
+//Note: as implemented this breaks Special Condition (bag must be limited by oldSep)
+func baseCaseDetK(H lib.Graph) lib.Decomp {
+	// log.Printf("Base case reached. Number of Special Edges %d\n", len(Sp))
+	var children lib.Node
+
+	switch len(H.Special) {
+	case 0:
+		return lib.Decomp{Graph: H, Root: lib.Node{Bag: H.Vertices(), Cover: H.Edges}}
+
+	case 1:
+		sp1 := H.Special[0]
+		children = lib.Node{Bag: sp1.Vertices(), Cover: sp1}
+	}
+
+	if H.Edges.Len() == 0 {
+		return lib.Decomp{Graph: H, Root: children}
+	}
+	return lib.Decomp{Graph: H, Root: lib.Node{Bag: H.Vertices(), Cover: H.Edges, Children: []lib.Node{children}}}
+}
+
+func (d *DetKDecomp) findDecomp(H lib.Graph, oldSep []int, recDepth int, stop *StopFlag) lib.Decomp {
+	recDepth = recDepth + 1 // increase the recursive depth
+
+	verticesCurrent := H.Vertices()
+	verticesExtended := append(verticesCurrent, oldSep...)
+	conn := lib.Inter(oldSep, verticesCurrent)
+	compVertices := lib.Diff(verticesCurrent, oldSep)
+	bound := d.Weights.Sort(lib.FilterVertices(d.Graph.Edges, conn))
+
+	// log.Printf("\n\nD Current oldSep: %v, Conn: %v\n", lib.PrintVertices(oldSep), lib.PrintVertices(conn))
+	// log.Printf("D Current SubGraph: %v ( %v hash) \n", H, H.Edges.Hash())
+	// log.Printf("D Current SubGraph: %v ( %v edges) (hash: %v )\n", H, H.Edges.Len(), H.Edges.Hash())
+	// log.Println("D Hedges ", H)
+	// log.Println("D Comp Vertices: ", lib.PrintVertices(compVertices))
+
+	d.cache.Trim(d.Memory)
+
+	// Base case if H <= K
+	if H.Edges.Len() == 0 && len(H.Special) <= 1 {
+		return baseCaseDetK(H)
+	}
+
+	gen := lib.NewCover(d.K, conn, bound, H.Edges.Vertices())
+	s := detKState{H: H, conn: conn, compVertices: compVertices, verticesExtended: verticesExtended,
+		recDepth: recDepth}
+
+	if d.parallel() {
+		return d.searchParallel(&gen, bound, s, stop)
+	}
+	return d.searchSequential(&gen, bound, s, stop)
+}
+
+// searchSequential tries the covers enumerated by gen as separators one after another
+func (d *DetKDecomp) searchSequential(gen *lib.Cover, bound lib.Edges, s detKState, stop *StopFlag) lib.Decomp {
+	var Vertices = make(map[int]*disjoint.Element)
+
+	for gen.HasNext && !d.stopped(stop) {
+		out := gen.NextSubset()
+
+		if out == -1 {
+			if gen.HasNext {
+				log.Panicln(" -1 but hasNext not false!")
+			}
+			continue
+		}
+
+		sep, inComp := coverSubset(gen, bound)
+
+		// if !Subset(conn, sep.Vertices()) {
+		//  log.Panicln("Cover messed up! 137")
+		// }
+		// log.Println("Next Cover ", sep)
+
+		decomp := d.trySeparator(sep, inComp, s, Vertices, stop)
+		if !reflect.DeepEqual(decomp, lib.Decomp{}) {
+			return decomp
+		}
+	}
+
+	return lib.Decomp{} // Reject if no separator could be found
+}
+
+// detKState holds the subgraph currently decomposed by DetKDecomp, together with the vertices needed to choose
+// separators for it
+type detKState struct {
+	H                lib.Graph
+	conn             []int
+	compVertices     []int
+	verticesExtended []int
+	recDepth         int
+}
+
+// coverSubset returns the edges currently selected by the cover, and for each whether it intersects the component
+func coverSubset(gen *lib.Cover, bound lib.Edges) (lib.Edges, []bool) {
+	inComp := make([]bool, len(gen.Subset))
+	for i, v := range gen.Subset {
+		inComp[i] = gen.InComp[v]
+	}
+
+	return lib.GetSubset(bound, gen.Subset), inComp
+}
+
+// trySeparator searches for a decomposition of the subgraph using the cover sep as separator, possibly extended by
+// an edge of the subgraph or, if SubEdge is set, replaced by subedges
+func (d *DetKDecomp) trySeparator(sep lib.Edges, inComp []bool, s detKState,
+	Vertices map[int]*disjoint.Element, stop *StopFlag) lib.Decomp {
+	H := s.H
+
+	addEdges := false
+
+	//check if sep "makes some progress" into separating H
+
+	if len(lib.Inter(sep.Vertices(), s.compVertices)) == 0 {
+		addEdges = true
+	}
+
+	if addEdges && d.K-sep.Len() <= 0 {
+		return lib.Decomp{}
+	}
+
+	for iAdd := 0; !addEdges || iAdd < H.Edges.Len(); iAdd++ {
+		var sepActual lib.Edges
+
+		if addEdges {
+			sepActual = lib.NewEdges(append(sep.Slice(), H.Edges.Slice()[iAdd]))
+		} else {
+			sepActual = sep
+		}
+
+		var sepConst []lib.Edge
+		var sepChanging []lib.Edge
+		if d.SubEdge {
+			for i := range inComp {
+				if inComp[i] {
+					sepChanging = append(sepChanging, sep.Slice()[i])
+				} else {
+					sepConst = append(sepConst, sep.Slice()[i])
+				}
+			}
+			if addEdges {
+				sepChanging = append(sepChanging, H.Edges.Slice()[iAdd])
+			}
+		}
+
+		decomp := d.trySubEdges(sepActual, sepConst, sepChanging, s, Vertices, stop)
+		if !reflect.DeepEqual(decomp, lib.Decomp{}) {
+			return decomp
+		}
+
+		if !addEdges {
+			break
+		}
+	}
+
+	return lib.Decomp{}
+}
+
+// trySubEdges searches for a decomposition of the subgraph using the separator sepActual and, if SubEdge is set and
+// this fails, the separators made of sepConst and subedges of sepChanging
+func (d *DetKDecomp) trySubEdges(sepActual lib.Edges, sepConst []lib.Edge, sepChanging []lib.Edge, s detKState,
+	Vertices map[int]*disjoint.Element, stop *StopFlag) lib.Decomp {
+	H := s.H
+	var sepSub *lib.SepSub
+	// sepActualOrigin := sepActual
+
+	for !d.stopped(stop) {
+
+		// log.Println("Sep chosen ", sepActual, " out ", out)
+		if !d.Weights.Allows(sepActual, d.CostBound) {
+			return lib.Decomp{}
+		}
+
+		comps, _, _ := H.GetComponents(sepActual, Vertices)
+
+		//check cache for previous encounters
+		if d.cache.CheckNegative(sepActual, comps) {
+			// log.Println("Skipping sep", sepActual, "due to cache.")
+			return lib.Decomp{}
+		}
+
+		// log.Printf("Comps of Sep: %v, len: %v\n", comps, len(comps))
+
+		bag := lib.Inter(sepActual.Vertices(), s.verticesExtended)
+
+		subtrees, failed := d.decomposeComps(comps, bag, s.recDepth, stop)
+		if failed == -1 {
+			return lib.Decomp{Graph: H, Root: lib.Node{Bag: bag, Cover: sepActual, Children: subtrees}}
+		}
+
+		// a search which was stopped doesn't show that no decomposition exists
+		if !d.stopped(stop) {
+			d.cache.AddNegative(sepActual, comps[failed])
+		}
+		// log.Printf("detK REJECTING %v: couldn't decompose %v  \n",
+		// 	lib.Graph{Edges: sepActual}, comps[failed])
+
+		if !d.SubEdge {
+			return lib.Decomp{}
+		}
+
+		if sepSub == nil {
+			sepSub = lib.GetSepSub(d.Graph.Edges, lib.NewEdges(sepChanging), d.K)
+		}
+
+		nextBalsepFound := false
+
+		for !nextBalsepFound {
+			if !sepSub.HasNext() {
+				// log.Printf("No SubSep found for %v  \n", Graph{Edges: sepActualOrigin})
+				return lib.Decomp{}
+			}
+			sepActual = sepSub.GetCurrent()
+			sepActual = lib.NewEdges(append(sepActual.Slice(), sepConst...))
+			if connectingSep(sepActual.Vertices(), s.conn, s.compVertices) {
+				nextBalsepFound = true
+			}
+		}
+		// log.Printf("Sub Sep chosen: %vof %v \n", lib.Graph{Edges: sepActual},
+		// 	lib.Graph{Edges: sepActualOrigin})
+	}
+
+	return lib.Decomp{}
+}
+
+// decomposeComps decomposes each component below the bag, returning the roots of their decompositions, or the index
+// of a component which couldn't be decomposed. If Parallel is set, the components are decomposed concurrently, as
+// far as goroutines of the pool are free, and the others are stopped once one fails. The index returned is then
+// that of the component which failed, not of one which was stopped because of it.
+func (d *DetKDecomp) decomposeComps(comps []lib.Graph, bag []int, recDepth int,
+	stop *StopFlag) ([]lib.Node, int) {
+	subtrees := make([]lib.Node, len(comps))
+
+	if !d.parallel() || len(comps) < 2 {
+		for i := range comps {
+			decomp := d.findDecomp(comps[i], bag, recDepth, stop)
+			if reflect.DeepEqual(decomp, lib.Decomp{}) {
+				return nil, i
+			}
+			//d.Cache.AddPositive(sepActual, comps[i])
+			// log.Printf("Produced Decomp: %v\n", decomp)
+			subtrees[i] = decomp.Root
+		}
+		return subtrees, -1
+	}
+
+	failed := stop.Child()
+	ch := make(chan decompInt, len(comps))
+	refuted := int32(-1) // the first component shown to have no decomposition, rather than stopped by another one
+
+	decompose := func(i int) lib.Decomp {
+		decomp := d.findDecomp(comps[i], bag, recDepth, failed)
+		if reflect.DeepEqual(decomp, lib.Decomp{}) && !d.stopped(failed) {
+			atomic.CompareAndSwapInt32(&refuted, -1, int32(i))
+			failed.Stop()
+		}
+		return decomp
+	}
+
+	for i := range comps {
+		if i == len(comps)-1 || !d.helper() { // decompose the component in this goroutine
+			ch <- decompInt{Decomp: decompose(i), Int: i}
+			continue
+		}
+
+		d.counter.Goroutine()
+		go func(i int) {
+			defer d.pool.Release()
+			ch <- decompInt{Decomp: decompose(i), Int: i}
+		}(i)
+	}
+
+	failure := -1
+	for range comps { // wait for all components, so no search outlives the call
+		out := <-ch
+		if reflect.DeepEqual(out.Decomp, lib.Decomp{}) {
+			failure = out.Int
+			continue
+		}
+		subtrees[out.Int] = out.Decomp.Root
+	}
+
+	switch {
+	case refuted != -1:
+		return nil, int(refuted)
+	case failure != -1: // only possible once stop was stopped
+		return nil, failure
+	}
+	return subtrees, -1
+}
+
+// detKCover is a cover chosen by the enumeration, together with the edges of it intersecting the component
+type detKCover struct {
+	sep    lib.Edges
+	inComp []bool
+}
+
+// searchParallel splits the covers enumerated by gen among several workers, each trying them as separators. Once a
+// worker finds a decomposition, the others are stopped. The workers are taken from the pool, so that their number
+// stays bounded over all levels of the recursion, and the covers are tried sequentially if none is free.
+func (d *DetKDecomp) searchParallel(gen *lib.Cover, bound lib.Edges, s detKState, stop *StopFlag) lib.Decomp {
+	found := stop.Child()
+	covers := make(chan detKCover)
+	var result lib.Decomp
+	var resultMux sync.Mutex
+	var wg sync.WaitGroup
+
+	workers := 0
+	for ; d.helper(); workers++ {
+		wg.Add(1)
+		d.counter.Goroutine()
+		go func() {
+			defer wg.Done()
+			defer d.pool.Release()
+			var Vertices = make(map[int]*disjoint.Element)
+
+			for cover := range covers {
+				if d.stopped(found) {
+					continue // drain the remaining covers
+				}
+
+				decomp := d.trySeparator(cover.sep, cover.inComp, s, Vertices, found)
+				if !reflect.DeepEqual(decomp, lib.Decomp{}) {
+					resultMux.Lock()
+					if reflect.DeepEqual(result, lib.Decomp{}) {
+						result = decomp
+						found.Stop()
+					}
+					resultMux.Unlock()
+				}
+			}
+		}()
+	}
+
+	if workers == 0 {
+		return d.searchSequential(gen, bound, s, stop)
+	}
+
+	for gen.HasNext && !d.stopped(found) {
+		out := gen.NextSubset()
+
+		if out == -1 {
+			if gen.HasNext {
+				log.Panicln(" -1 but hasNext not false!")
+			}
+			continue
+		}
+
+		sep, inComp := coverSubset(gen, bound)
+		covers <- detKCover{sep: sep, inComp: inComp}
+	}
+	close(covers)
+	wg.Wait()
+
+	return result
+}
+
+// workerPool bounds the number of goroutines DetKDecomp starts to explore covers and components concurrently. A nil
+// pool has no goroutines to offer.
+type workerPool struct {
+	inUse int32
+}
+
+// TryAcquire reserves a goroutine if fewer than limit are in use, without waiting for one to become free
+func (p *workerPool) TryAcquire(limit int) bool {
+	if p == nil {
+		return false
+	}
+	for {
+		inUse := atomic.LoadInt32(&p.inUse)
+		if int(inUse) >= limit {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&p.inUse, inUse, inUse+1) {
+			return true
+		}
+	}
+}
+
+// Release returns a goroutine reserved before
+func (p *workerPool) Release() {
+	atomic.AddInt32(&p.inUse, -1)
+}
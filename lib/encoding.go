@@ -0,0 +1,451 @@
+package lib
+
+// encoding.go keeps track of the identifiers used in the input, so that any output can refer to vertices and
+// edges by their original names, instead of the integers used internally
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+)
+
+// An Encoding maps the integers used internally for vertices and edges to their names in the input, and back
+type Encoding struct {
+	names map[int]string
+	ids   map[string]int
+}
+
+// NewEncoding produces an Encoding from a mapping of names to integers, as produced by the parser
+func NewEncoding(ids map[string]int) Encoding {
+	e := Encoding{names: make(map[int]string, len(ids)), ids: make(map[string]int, len(ids))}
+
+	for name, i := range ids {
+		e.names[i] = name
+		e.ids[name] = i
+	}
+
+	return e
+}
+
+// Name returns the original identifier of a vertex or edge. Integers not present in the encoding, such as
+// vertices introduced during preprocessing, are simply printed as numbers
+func (e Encoding) Name(i int) string {
+	if name, ok := e.names[i]; ok {
+		return name
+	}
+	return strconv.Itoa(i)
+}
+
+// ID returns the integer used internally for a vertex or edge name
+func (e Encoding) ID(name string) (int, bool) {
+	i, ok := e.ids[name]
+	return i, ok
+}
+
+// Len returns the number of names in the encoding
+func (e Encoding) Len() int {
+	return len(e.names)
+}
+
+// Vertices prints a list of vertices, using their original names
+func (e Encoding) Vertices(vertices []int) string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("(")
+	for i, v := range vertices {
+		buffer.WriteString(e.Name(v))
+		if i != len(vertices)-1 {
+			buffer.WriteString(", ")
+		}
+	}
+	buffer.WriteString(")")
+
+	return buffer.String()
+}
+
+// Edge prints an edge by its original name, or as a list of vertices if it is unnamed (e.g. a special edge)
+func (e Encoding) Edge(edge lib.Edge) string {
+	if edge.Name > 0 {
+		if name, ok := e.names[edge.Name]; ok {
+			return name
+		}
+	}
+	return e.Vertices(edge.Vertices)
+}
+
+// FullEdge always prints the list of vertices of an edge, even if the edge is named
+func (e Encoding) FullEdge(edge lib.Edge) string {
+	if edge.Name > 0 {
+		return e.Edge(lib.Edge{Name: edge.Name}) + " " + e.Vertices(edge.Vertices)
+	}
+	return e.Vertices(edge.Vertices)
+}
+
+// Edges prints a set of edges
+func (e Encoding) Edges(edges lib.Edges) string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("{")
+	for i, edge := range edges.Slice() {
+		buffer.WriteString(e.Edge(edge))
+		if i != edges.Len()-1 {
+			buffer.WriteString(", ")
+		}
+	}
+	buffer.WriteString("}")
+
+	return buffer.String()
+}
+
+// Graph prints a graph, including any special edges
+func (e Encoding) Graph(g lib.Graph) string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString(e.Edges(g.Edges))
+
+	if len(g.Special) > 0 {
+		buffer.WriteString(" & Special Edges [")
+		for i := range g.Special {
+			buffer.WriteString(e.Edges(g.Special[i]))
+			if i != len(g.Special)-1 {
+				buffer.WriteString(", ")
+			}
+		}
+		buffer.WriteString(" ]")
+	}
+
+	return buffer.String()
+}
+
+func indent(i int) string {
+	return strings.Repeat("\t", i)
+}
+
+func (e Encoding) node(n lib.Node, i int) string {
+	var buffer bytes.Buffer
+
+	bag := e.Vertices(n.Bag)
+	buffer.WriteString("\n" + indent(i) + "Bag: {" + bag[1:len(bag)-1] + "}")
+	buffer.WriteString("\n" + indent(i) + "Cover: " + e.Edges(n.Cover) + "\n")
+
+	if n.Cost != 0 {
+		buffer.WriteString(indent(i) + "Cost: " + fmt.Sprintf("%.2f", n.Cost) + "\n")
+	}
+	if len(n.Children) > 0 {
+		buffer.WriteString(indent(i) + "Children: " + strconv.Itoa(len(n.Children)) + "\n" + indent(i) + "[")
+		for _, c := range n.Children {
+			buffer.WriteString(e.node(c, i+1))
+		}
+		buffer.WriteString(indent(i) + "]\n")
+	}
+
+	return buffer.String()
+}
+
+// Node prints the tree rooted at a node, in the same layout as used by BalancedGo
+func (e Encoding) Node(n lib.Node) string {
+	return e.node(n, 0)
+}
+
+// Decomp prints a decomposition
+func (e Encoding) Decomp(d lib.Decomp) string {
+	return e.Node(d.Root)
+}
+
+// GML exports a decomposition as a string in GML format. Nodes are numbered in depth-first order.
+func (e Encoding) GML(d lib.Decomp) string {
+	var nodes bytes.Buffer
+	var arcs bytes.Buffer
+	counter := 0
+
+	var visit func(n lib.Node) int
+	visit = func(n lib.Node) int {
+		counter++
+		id := counter
+
+		nodes.WriteString("  node [\n    id " + fmt.Sprint(id) +
+			"\n    label \"" + e.Edges(n.Cover) + " " + e.Vertices(n.Bag) +
+			"\"\n    vgj [\n      labelPosition \"in\"\n      shape \"Rectangle\"\n    ]\n  ]\n\n")
+
+		for _, c := range n.Children {
+			child := visit(c)
+			arcs.WriteString("  edge [\n    source " + fmt.Sprint(id) +
+				"\n    target " + fmt.Sprint(child) + "\n  ]\n\n")
+		}
+
+		return id
+	}
+	visit(d.Root)
+
+	result := "graph [\n\n  directed 0\n\n" + nodes.String() + arcs.String() + "\n]\n"
+
+	//simple fix to match DetK GML output exactly
+	result = strings.ReplaceAll(result, "(", "{")
+	result = strings.ReplaceAll(result, ")", "}")
+	return result
+}
+
+// gmlNames splits a list of names as printed in a label of GML, such as "{a, b}"
+func gmlNames(list string) []string {
+	list = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(list, "{"), "}"))
+	if list == "" {
+		return nil
+	}
+
+	var output []string
+	for _, name := range strings.Split(list, ",") {
+		output = append(output, strings.TrimSpace(name))
+	}
+	return output
+}
+
+// ReadGML reads a decomposition of g in the GML format produced by the GML method, using the names of the
+// encoding. The root is the only node which is not the target of an arc.
+func ReadGML(r io.Reader, g lib.Graph, encoding Encoding) (lib.Decomp, error) {
+	edges := make(map[int]lib.Edge)
+	for _, e := range g.Edges.Slice() {
+		edges[e.Name] = e
+	}
+
+	type gmlNode struct {
+		node   lib.Node
+		parent int
+	}
+	nodes := make(map[int]*gmlNode)
+	var ids []int
+	var arcs [][2]int
+
+	block, depth := "", 0
+	id, source, target := -1, -1, -1
+	label, hasLabel := "", false
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case fields[len(fields)-1] == "[":
+			depth++
+			if depth == 2 {
+				block = fields[0]
+				id, source, target, hasLabel = -1, -1, -1, false
+			}
+			continue
+		case fields[0] == "]":
+			depth--
+			if depth != 1 {
+				continue
+			}
+		default:
+			if depth != 2 || len(fields) < 2 {
+				continue // attributes of the graph, or nested ones such as vgj
+			}
+			value := strings.TrimSpace(strings.TrimPrefix(text, fields[0]))
+			var err error
+			switch fields[0] {
+			case "id":
+				id, err = strconv.Atoi(value)
+			case "source":
+				source, err = strconv.Atoi(value)
+			case "target":
+				target, err = strconv.Atoi(value)
+			case "label":
+				label, hasLabel = strings.Trim(value, "\""), true
+			}
+			if err != nil {
+				return lib.Decomp{}, fmt.Errorf("line %d: malformed %s", line, fields[0])
+			}
+			continue
+		}
+
+		// the end of a node or an edge
+		switch block {
+		case "node":
+			split := strings.Index(label, "} {")
+			if id < 0 || !hasLabel || split < 0 {
+				return lib.Decomp{}, fmt.Errorf("line %d: node without id or label", line)
+			}
+			if _, ok := nodes[id]; ok {
+				return lib.Decomp{}, fmt.Errorf("line %d: duplicate node %d", line, id)
+			}
+
+			var n lib.Node
+			var cover []lib.Edge
+			for _, name := range gmlNames(label[:split+1]) {
+				i, ok := encoding.ID(name)
+				if _, isEdge := edges[i]; !ok || !isEdge {
+					return lib.Decomp{}, fmt.Errorf("line %d: unknown edge %v", line, name)
+				}
+				cover = append(cover, edges[i])
+			}
+			n.Cover = lib.NewEdges(cover)
+			for _, name := range gmlNames(label[split+2:]) {
+				i, ok := encoding.ID(name)
+				if !ok || !lib.Subset([]int{i}, g.Vertices()) {
+					return lib.Decomp{}, fmt.Errorf("line %d: unknown vertex %v", line, name)
+				}
+				n.Bag = append(n.Bag, i)
+			}
+
+			nodes[id] = &gmlNode{node: n, parent: -1}
+			ids = append(ids, id)
+		case "edge":
+			if source < 0 || target < 0 {
+				return lib.Decomp{}, fmt.Errorf("line %d: edge without source or target", line)
+			}
+			arcs = append(arcs, [2]int{source, target})
+		}
+		block = ""
+	}
+	if err := scanner.Err(); err != nil {
+		return lib.Decomp{}, err
+	}
+	if len(nodes) == 0 {
+		return lib.Decomp{}, fmt.Errorf("no nodes found")
+	}
+
+	children := make(map[int][]int)
+	for _, a := range arcs {
+		if nodes[a[0]] == nil || nodes[a[1]] == nil {
+			return lib.Decomp{}, fmt.Errorf("edge between unknown nodes %d and %d", a[0], a[1])
+		}
+		if nodes[a[1]].parent >= 0 {
+			return lib.Decomp{}, fmt.Errorf("node %d has more than one parent", a[1])
+		}
+		nodes[a[1]].parent = a[0]
+		children[a[0]] = append(children[a[0]], a[1])
+	}
+
+	root := -1
+	for _, i := range ids {
+		if nodes[i].parent < 0 {
+			if root >= 0 {
+				return lib.Decomp{}, fmt.Errorf("nodes %d and %d both have no parent", root, i)
+			}
+			root = i
+		}
+	}
+	if root < 0 {
+		return lib.Decomp{}, fmt.Errorf("no root found, the edges form a cycle")
+	}
+
+	visited := 0
+	var build func(i int) lib.Node
+	build = func(i int) lib.Node {
+		visited++
+		output := nodes[i].node
+		for _, j := range children[i] {
+			output.Children = append(output.Children, build(j))
+		}
+		return output
+	}
+	decomp := lib.Decomp{Graph: g, Root: build(root)}
+	if visited != len(nodes) {
+		return lib.Decomp{}, fmt.Errorf("edges don't form a tree")
+	}
+
+	return decomp, nil
+}
+
+// HyperBench exports a graph in HyperBench format, using the original names, quoted where needed
+func (e Encoding) HyperBench(g lib.Graph) string {
+	var buffer bytes.Buffer
+
+	for i, edge := range g.Edges.Slice() {
+		buffer.WriteString(hyperBenchName(e.Edge(lib.Edge{Name: edge.Name})) + "(")
+		for j, v := range edge.Vertices {
+			if j > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(hyperBenchName(e.Name(v)))
+		}
+		buffer.WriteString(")")
+		if i != g.Edges.Len()-1 {
+			buffer.WriteString(",\n")
+		}
+	}
+
+	buffer.WriteString(".\n")
+	return buffer.String()
+}
+
+// parserMux serialises calls to the parser of BalancedGo, which numbers vertices using package-level variables
+var parserMux sync.Mutex
+
+// GetGraph parses a string in HyperBench format into a graph, and returns the encoding of the names used.
+// Unlike the parser of BalancedGo, malformed input produces an error instead of a panic. It is safe to call
+// concurrently, as are the readers of the other formats built on it.
+func GetGraph(s string) (graph lib.Graph, encoding Encoding, err error) {
+	parserMux.Lock()
+	defer parserMux.Unlock()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("couldn't parse input: %v", r)
+		}
+	}()
+
+	graph, pGraph := lib.GetGraph(s)
+
+	return graph, NewEncoding(pGraph.Encoding), nil
+}
+
+// GetGraphPACE parses a string in PACE 2019 format into a graph, and returns the encoding of the names used.
+// Edges and vertices are named "E<n>" and "V<n>" respectively, matching the names BalancedGo uses for the format.
+func GetGraphPACE(s string) (lib.Graph, Encoding, error) {
+	var buffer bytes.Buffer
+	headerFound := false
+	first := true
+
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(s)+1)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) == 0 || fields[0] == "c" || strings.HasPrefix(fields[0], "//") {
+			continue // skip empty lines and comments
+		}
+		if fields[0] == "p" {
+			if len(fields) != 4 || fields[1] != "htd" || headerFound {
+				return lib.Graph{}, Encoding{}, fmt.Errorf("line %d: malformed header", line)
+			}
+			headerFound = true
+			continue
+		}
+		if !headerFound {
+			return lib.Graph{}, Encoding{}, fmt.Errorf("line %d: edge before header", line)
+		}
+
+		var vertices []string
+		for _, f := range fields {
+			if _, err := strconv.Atoi(f); err != nil {
+				return lib.Graph{}, Encoding{}, fmt.Errorf("line %d: %v is not a number", line, f)
+			}
+			vertices = append(vertices, "V"+f)
+		}
+
+		if !first {
+			buffer.WriteString(",\n")
+		}
+		first = false
+		buffer.WriteString("E" + fields[0] + "(" + strings.Join(vertices[1:], ",") + ")")
+	}
+
+	if !headerFound {
+		return lib.Graph{}, Encoding{}, fmt.Errorf("missing header")
+	}
+	buffer.WriteString(".")
+
+	return GetGraph(buffer.String())
+}
@@ -0,0 +1,302 @@
+package lib
+
+// Enumeration of several decompositions with LogKDecomp, continuing the search after each success
+
+import (
+	"log"
+	"reflect"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	"github.com/cem-okulmus/disjoint"
+)
+
+// copyNode produces a deep copy of the tree below n, as attaching subtrees modifies the nodes involved
+func copyNode(n lib.Node) lib.Node {
+	output := n
+
+	if len(n.Children) > 0 {
+		output.Children = make([]lib.Node, len(n.Children))
+		for i := range n.Children {
+			output.Children[i] = copyNode(n.Children[i])
+		}
+	}
+
+	return output
+}
+
+// decomposable checks in parallel that each component has some decomposition, as otherwise there is no point in
+// enumerating combinations of their decompositions. Failures are added to the cache, unless the search was stopped.
+func (l *LogKDecomp) decomposable(sep lib.Edges, comps []lib.Graph, conns [][]int, allowedFull lib.Edges) bool {
+	ch := make(chan decompInt)
+
+	for x := range comps {
+		l.counter.Goroutine()
+		go func(x int) {
+			var out decompInt
+			out.Decomp = l.findDecomp(comps[x], conns[x], allowedFull)
+			out.Int = x
+			ch <- out
+		}(x)
+	}
+
+	output := true
+	for range comps {
+		out := <-ch
+		if reflect.DeepEqual(out.Decomp, lib.Decomp{}) {
+			output = false
+			if !l.stopped() { // otherwise the component might be decomposable after all
+				l.cache.AddNegative(sep, comps[out.Int])
+			}
+		}
+	}
+
+	return output && !l.stopped()
+}
+
+// enumProduct enumerates all combinations of decompositions of the components, calling yield with the roots of
+// each combination. It returns false if yield or the stop flag stopped the enumeration.
+func (l *LogKDecomp) enumProduct(comps []lib.Graph, conns [][]int, allowedFull lib.Edges, prefix []lib.Node,
+	yield func([]lib.Node) bool) bool {
+	if l.stopped() {
+		return false
+	}
+	if len(prefix) == len(comps) {
+		return yield(append([]lib.Node{}, prefix...))
+	}
+
+	i := len(prefix)
+	return l.enumDecomp(comps[i], conns[i], allowedFull, func(root lib.Node) bool {
+		return l.enumProduct(comps, conns, allowedFull, append(prefix, root), yield)
+	})
+}
+
+// enumDecomp follows findDecomp, but calls yield with the root of each decomposition found instead of returning the
+// first one. Components are decomposed sequentially, to produce all combinations of their decompositions. It
+// returns false if yield or the stop flag stopped the enumeration. Subproblems are not handed over to DetKDecomp
+// when memory runs low, as it would only produce one of their decompositions, but the cache is still trimmed.
+func (l *LogKDecomp) enumDecomp(H lib.Graph, Conn []int, allowedFull lib.Edges, yield func(lib.Node) bool) bool {
+	if !lib.Subset(Conn, H.Vertices()) {
+		log.Panicln("Conn invariant violated.")
+	}
+	if l.stopped() {
+		return false
+	}
+	l.cache.Trim(l.Memory)
+
+	// Base Case
+	if l.baseCaseCheck(H, allowedFull.Len()) {
+		decomp := l.baseCase(H, allowedFull.Len())
+		if reflect.DeepEqual(decomp, lib.Decomp{}) {
+			return true
+		}
+		return yield(decomp.Root)
+	}
+	//all vertices within (H ∪ Sp)
+	VerticesH := H.Vertices()
+
+	allowed := l.Weights.Sort(lib.FilterVertices(allowedFull, VerticesH))
+
+	// Set up iterator for child
+	parallelSearch, pred := l.childSearch(&H, &allowed)
+	var Vertices = make(map[int]*disjoint.Element)
+
+CHILD:
+	for ; !parallelSearch.SearchEnded() && !l.stopped(); parallelSearch.FindNext(pred) {
+
+		childλ := lib.GetSubset(allowed, parallelSearch.GetResult())
+		compsε, _, _ := H.GetComponents(childλ, Vertices)
+
+		// Check if child is possible root
+		if lib.Subset(Conn, childλ.Vertices()) {
+			childχ := lib.Inter(childλ.Vertices(), VerticesH)
+
+			// check cache for previous encounters
+			if l.cache.CheckNegative(childλ, compsε) {
+				continue CHILD
+			}
+
+			conns := make([][]int, len(compsε))
+			for y := range compsε {
+				conns[y] = lib.Inter(compsε[y].Vertices(), childχ)
+			}
+			if !l.decomposable(childλ, compsε, conns, allowedFull) {
+				continue CHILD
+			}
+
+			if !l.enumProduct(compsε, conns, allowedFull, nil, func(subtrees []lib.Node) bool {
+				return yield(lib.Node{Bag: childχ, Cover: childλ, Children: subtrees})
+			}) {
+				return false
+			}
+			continue CHILD
+		}
+
+		// Set up iterator for parent
+		allowedParent := lib.FilterVertices(allowed, append(Conn, childλ.Vertices()...))
+		parentalSearch, predPar := l.parentSearch(&H, &allowedParent, Conn, childλ)
+	PARENT:
+		for ; !parentalSearch.SearchEnded() && !l.stopped(); parentalSearch.FindNext(predPar) {
+
+			parentλ := lib.GetSubset(allowedParent, parentalSearch.GetResult())
+			compsπ, _, isolatedEdges := H.GetComponents(parentλ, Vertices)
+
+			// Check if parent is un-balanced
+			compLowIndex, foundLow := l.lowComponent(H, compsπ, parentλ)
+			if !foundLow {
+				log.Panicln("the parallel search didn't actually find a valid parent")
+			}
+
+			compLow := compsπ[compLowIndex]
+			vertCompLow := compLow.Vertices()
+			childχ := lib.Inter(childλ.Vertices(), vertCompLow)
+
+			// determine which componenents of child are inside comp_low
+			compsε, _, _ := compLow.GetComponents(childλ, Vertices)
+
+			// check chache for previous encounters
+			if l.cache.CheckNegative(childλ, compsε) {
+				continue PARENT
+			}
+
+			conns := make([][]int, len(compsε))
+			for x := range compsε {
+				conns[x] = lib.Inter(compsε[x].Vertices(), childχ)
+			}
+			if !l.decomposable(childλ, compsε, conns, allowedFull) {
+				continue PARENT
+			}
+
+			var compUp lib.Graph
+			tempEdgeSlice, tempSpecialSlice := upperComponent(compsπ, compLowIndex, isolatedEdges)
+
+			specialChild := lib.NewEdges([]lib.Edge{{Vertices: childχ}})
+
+			// combine each decomposition of the upper component with each below the child
+			below := func(up *lib.Node) bool {
+				return l.enumProduct(compsε, conns, allowedFull, nil, func(subtrees []lib.Node) bool {
+					rootChild := lib.Node{Bag: childχ, Cover: childλ, Children: subtrees}
+					if up == nil {
+						return yield(rootChild)
+					}
+					return yield(attachingSubtrees(copyNode(*up), rootChild, specialChild))
+				})
+			}
+
+			switch {
+			case len(tempEdgeSlice) == 0:
+				if !below(nil) {
+					return false
+				}
+			case len(compsπ) == 1: // if no comps_p, other than comp_low, just use parent as is
+				up := lib.Node{Bag: lib.Inter(parentλ.Vertices(), VerticesH), Cover: parentλ,
+					Children: []lib.Node{{Bag: specialChild.Vertices(), Cover: childλ}}}
+				if !below(&up) {
+					return false
+				}
+			default:
+				compUp.Edges = lib.NewEdges(tempEdgeSlice)
+				compUp.Special = append(tempSpecialSlice, specialChild)
+
+				//Reducing the allowed edges
+				allowedReduced := allowedFull.Diff(compLow.Edges)
+
+				if !l.enumDecomp(compUp, Conn, allowedReduced, func(up lib.Node) bool {
+					return below(&up)
+				}) {
+					return false
+				}
+			}
+		}
+	}
+
+	// exhausted search space, unless stopped
+	return !l.stopped()
+}
+
+// Enumerate calls yield with each decomposition found, until yield returns false or the search space is
+// exhausted. Decompositions equivalent to one produced earlier are skipped. The enumeration also ends once the
+// search is stopped.
+func (l *LogKDecomp) Enumerate(equiv Equivalence, yield func(lib.Decomp) bool) {
+	l.cache.Init()
+	l.counter.Init()
+	seen := make(map[string]bool)
+
+	allowed := l.Graph.Edges
+	if l.TreeDecomp {
+		allowed = VertexEdges(l.Graph.Vertices())
+	}
+
+	l.enumDecomp(l.Graph, []int{}, allowed, func(root lib.Node) bool {
+		decomp := lib.Decomp{Graph: l.Graph, Root: copyNode(root)}
+
+		key := equiv.Key(decomp)
+		if seen[key] {
+			return true
+		}
+		seen[key] = true
+
+		return yield(decomp)
+	})
+}
+
+// A DecompIterator streams the decompositions of an enumeration, computing each only once it is requested
+type DecompIterator struct {
+	next    chan lib.Decomp
+	request chan bool
+	current lib.Decomp
+	done    bool
+}
+
+// Iterator starts an enumeration of the decompositions, as in Enumerate, which proceeds with each call of Next
+func (l *LogKDecomp) Iterator(equiv Equivalence) *DecompIterator {
+	it := DecompIterator{next: make(chan lib.Decomp), request: make(chan bool)}
+
+	go func() {
+		defer close(it.next)
+
+		if !<-it.request {
+			return
+		}
+		l.Enumerate(equiv, func(decomp lib.Decomp) bool {
+			it.next <- decomp
+			return <-it.request
+		})
+	}()
+
+	return &it
+}
+
+// Next computes the next decomposition, and returns false once there are no more
+func (it *DecompIterator) Next() bool {
+	if it.done {
+		return false
+	}
+
+	it.request <- true
+	decomp, ok := <-it.next
+	if !ok {
+		it.done = true
+		return false
+	}
+
+	it.current = decomp
+	return true
+}
+
+// Decomp returns the decomposition computed by the last call of Next
+func (it *DecompIterator) Decomp() lib.Decomp {
+	return it.current
+}
+
+// Close stops the enumeration. This is needed to release its resources if the iterator is abandoned before Next
+// returns false.
+func (it *DecompIterator) Close() {
+	if it.done {
+		return
+	}
+	it.done = true
+
+	it.request <- false
+	for range it.next { // wait for the enumeration to end
+	}
+}
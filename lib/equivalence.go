@@ -0,0 +1,112 @@
+package lib
+
+// equivalence.go determines when two decompositions are considered the same, used to skip duplicates when
+// enumerating decompositions
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+)
+
+// An Equivalence determines which decompositions are considered the same
+type Equivalence int
+
+// The supported equivalences
+const (
+	EquivCovers   Equivalence = iota // same rooted tree, with the same bags and covers
+	EquivBags                        // same rooted tree with the same bags, covers are ignored
+	EquivUnrooted                    // same tree with the same bags, regardless of which node is the root
+)
+
+// GetEquivalence returns the equivalence of the given name
+func GetEquivalence(name string) (Equivalence, error) {
+	switch strings.ToLower(name) {
+	case "covers":
+		return EquivCovers, nil
+	case "bags":
+		return EquivBags, nil
+	case "unrooted":
+		return EquivUnrooted, nil
+	}
+
+	return 0, fmt.Errorf("unknown equivalence %v", name)
+}
+
+// label produces a canonical representation of a single node
+func (e Equivalence) label(n lib.Node) string {
+	bag := append([]int{}, n.Bag...)
+	sort.Ints(bag)
+
+	var sb strings.Builder
+	for _, v := range bag {
+		sb.WriteString(strconv.Itoa(v) + ",")
+	}
+
+	if e == EquivCovers {
+		var cover []int
+		for _, edge := range n.Cover.Slice() {
+			cover = append(cover, edge.Name)
+		}
+		sort.Ints(cover)
+
+		sb.WriteString("|")
+		for _, edge := range cover {
+			sb.WriteString(strconv.Itoa(edge) + ",")
+		}
+	}
+
+	return sb.String()
+}
+
+// canonical produces a representation of the tree rooted at node i, which doesn't depend on the order of children
+func canonical(labels []string, neighbours [][]int, i int, from int) string {
+	var children []string
+	for _, j := range neighbours[i] {
+		if j != from {
+			children = append(children, canonical(labels, neighbours, j, i))
+		}
+	}
+	sort.Strings(children)
+
+	return "(" + labels[i] + strings.Join(children, "") + ")"
+}
+
+// Key produces a string which is the same for two decompositions iff they are equivalent
+func (e Equivalence) Key(d lib.Decomp) string {
+	var labels []string
+	var neighbours [][]int
+
+	var visit func(n lib.Node, parent int)
+	visit = func(n lib.Node, parent int) {
+		i := len(labels)
+		labels = append(labels, e.label(n))
+		neighbours = append(neighbours, []int{})
+		if parent >= 0 {
+			neighbours[parent] = append(neighbours[parent], i)
+			neighbours[i] = append(neighbours[i], parent)
+		}
+
+		for _, c := range n.Children {
+			visit(c, i)
+		}
+	}
+	visit(d.Root, -1)
+
+	if e != EquivUnrooted {
+		return canonical(labels, neighbours, 0, -1)
+	}
+
+	// use the smallest representation over all choices of root
+	output := canonical(labels, neighbours, 0, -1)
+	for i := 1; i < len(labels); i++ {
+		if key := canonical(labels, neighbours, i, -1); key < output {
+			output = key
+		}
+	}
+
+	return output
+}
@@ -0,0 +1,370 @@
+package lib
+
+// eval.go implements the evaluation of a conjunctive query over a hypertree decomposition of its hypergraph,
+// following the algorithm of Yannakakis: relations for the bags are built from their covers, reduced by semi-joins
+// along the tree and finally joined
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+)
+
+// A Relation is a set of tuples over a list of attributes, where each attribute is a vertex of the hypergraph
+type Relation struct {
+	Attributes []int
+	Tuples     [][]string
+}
+
+// A Database assigns a relation to each edge of a hypergraph, using the edge names as keys
+type Database map[int]Relation
+
+// Len returns the number of tuples of the relation
+func (r Relation) Len() int {
+	return len(r.Tuples)
+}
+
+// key produces a string identifying the values of a tuple at the given positions
+func key(tuple []string, positions []int) string {
+	var sb strings.Builder
+
+	for _, p := range positions {
+		sb.WriteString(tuple[p])
+		sb.WriteByte(0) // separator which can't occur in values read from CSV
+	}
+
+	return sb.String()
+}
+
+// positions returns the positions of the attributes in r
+func (r Relation) positions(attributes []int) []int {
+	var output []int
+
+	for _, a := range attributes {
+		for i := range r.Attributes {
+			if r.Attributes[i] == a {
+				output = append(output, i)
+				break
+			}
+		}
+	}
+
+	return output
+}
+
+// Project restricts the relation to the given attributes, removing any duplicate tuples. Attributes not present in
+// the relation are ignored.
+func (r Relation) Project(attributes []int) Relation {
+	attributes = lib.Inter(attributes, r.Attributes)
+	pos := r.positions(attributes)
+
+	output := Relation{Attributes: attributes}
+	seen := make(map[string]bool)
+
+	for _, t := range r.Tuples {
+		k := key(t, pos)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		tuple := make([]string, len(pos))
+		for i, p := range pos {
+			tuple[i] = t[p]
+		}
+		output.Tuples = append(output.Tuples, tuple)
+	}
+
+	return output
+}
+
+// Join computes the natural join of two relations, using a hash join on their common attributes
+func (r Relation) Join(other Relation) Relation {
+	common := lib.Inter(r.Attributes, other.Attributes)
+	extra := lib.Diff(other.Attributes, common)
+
+	posR := r.positions(common)
+	posO := other.positions(common)
+	posExtra := other.positions(extra)
+
+	index := make(map[string][][]string)
+	for _, t := range other.Tuples {
+		k := key(t, posO)
+		index[k] = append(index[k], t)
+	}
+
+	output := Relation{Attributes: append(append([]int{}, r.Attributes...), extra...)}
+	for _, t := range r.Tuples {
+		for _, o := range index[key(t, posR)] {
+			tuple := make([]string, 0, len(output.Attributes))
+			tuple = append(tuple, t...)
+			for _, p := range posExtra {
+				tuple = append(tuple, o[p])
+			}
+			output.Tuples = append(output.Tuples, tuple)
+		}
+	}
+
+	return output
+}
+
+// Semijoin keeps only those tuples of the relation which join with some tuple of the other relation
+func (r Relation) Semijoin(other Relation) Relation {
+	common := lib.Inter(r.Attributes, other.Attributes)
+	posR := r.positions(common)
+	posO := other.positions(common)
+
+	index := make(map[string]bool)
+	for _, t := range other.Tuples {
+		index[key(t, posO)] = true
+	}
+
+	output := Relation{Attributes: r.Attributes}
+	for _, t := range r.Tuples {
+		if index[key(t, posR)] {
+			output.Tuples = append(output.Tuples, t)
+		}
+	}
+
+	return output
+}
+
+// ReadCSV reads the relation of an edge from CSV, where the columns correspond to the vertices of the edge in
+// the order of the input. If a vertex occurs several times in the edge, only the rows agreeing on all its
+// occurrences are kept.
+func ReadCSV(r io.Reader, edge lib.Edge) (Relation, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = len(edge.Vertices)
+
+	var raw Relation
+	raw.Attributes = edge.Vertices
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Relation{}, err
+		}
+
+		raw.Tuples = append(raw.Tuples, record)
+	}
+
+	// enforce equality for repeated vertices, then remove the duplicate columns
+	first := make(map[int]int)
+	var filtered [][]string
+OUTER:
+	for _, t := range raw.Tuples {
+		for i, v := range edge.Vertices {
+			if j, ok := first[v]; ok && j != i && t[j] != t[i] {
+				continue OUTER
+			} else if !ok {
+				first[v] = i
+			}
+		}
+		filtered = append(filtered, t)
+	}
+	raw.Tuples = filtered
+
+	return raw.Project(lib.RemoveDuplicates(append([]int{}, edge.Vertices...))), nil
+}
+
+// LoadDatabase reads the relations of all edges of a graph from a directory, where each edge is stored in a CSV
+// file named after the edge, using the original names of the input
+func LoadDatabase(dir string, g lib.Graph, encoding Encoding) (Database, error) {
+	db := make(Database)
+
+	for _, e := range g.Edges.Slice() {
+		name := encoding.Name(e.Name)
+		f, err := os.Open(filepath.Join(dir, name+".csv"))
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := ReadCSV(f, e)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("relation %v: %v", name, err)
+		}
+
+		db[e.Name] = r
+	}
+
+	return db, nil
+}
+
+// WriteCSV writes the relation as CSV, with a header naming the attributes
+func (r Relation) WriteCSV(w io.Writer, encoding Encoding) error {
+	writer := csv.NewWriter(w)
+
+	var header []string
+	for _, a := range r.Attributes {
+		header = append(header, encoding.Name(a))
+	}
+	writer.Write(header)
+
+	for _, t := range r.Tuples {
+		writer.Write(t)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// A joinTree is a decomposition in a form suitable for evaluation, with the nodes stored in pre-order
+type joinTree struct {
+	nodes    []lib.Node
+	parent   []int
+	children [][]int
+	rels     []Relation
+}
+
+func newJoinTree(d lib.Decomp) joinTree {
+	var t joinTree
+
+	var visit func(n lib.Node, parent int)
+	visit = func(n lib.Node, parent int) {
+		i := len(t.nodes)
+		t.nodes = append(t.nodes, n)
+		t.parent = append(t.parent, parent)
+		t.children = append(t.children, []int{})
+		if parent >= 0 {
+			t.children[parent] = append(t.children[parent], i)
+		}
+
+		for _, c := range n.Children {
+			visit(c, i)
+		}
+	}
+	visit(d.Root, -1)
+
+	return t
+}
+
+// ErrEmptyDecomp is returned when evaluation is attempted over a missing decomposition
+var ErrEmptyDecomp = errors.New("no decomposition to evaluate over")
+
+// AssignEdges determines for each edge of the graph a node of the decomposition whose bag contains all of its
+// vertices. Nodes are identified by their position in a pre-order traversal of the decomposition.
+func AssignEdges(d lib.Decomp, g lib.Graph) (map[int]int, error) {
+	t := newJoinTree(d)
+	output := make(map[int]int)
+
+OUTER:
+	for _, e := range g.Edges.Slice() {
+		for i := range t.nodes {
+			if lib.Subset(e.Vertices, t.nodes[i].Bag) {
+				output[e.Name] = i
+				continue OUTER
+			}
+		}
+		return nil, fmt.Errorf("edge %v is not covered by the decomposition", e)
+	}
+
+	return output, nil
+}
+
+// bagRelations computes the relation of each bag, by joining the relations of its cover, joining any further edges
+// assigned to the node and projecting the result to the bag
+func bagRelations(d lib.Decomp, g lib.Graph, db Database) (joinTree, error) {
+	if reflect.DeepEqual(d, lib.Decomp{}) {
+		return joinTree{}, ErrEmptyDecomp
+	}
+
+	t := newJoinTree(d)
+	assignment, err := AssignEdges(d, g)
+	if err != nil {
+		return joinTree{}, err
+	}
+
+	assigned := make([][]int, len(t.nodes))
+	for _, e := range g.Edges.Slice() {
+		assigned[assignment[e.Name]] = append(assigned[assignment[e.Name]], e.Name)
+	}
+
+	for i := range t.nodes {
+		var names []int
+		for _, e := range t.nodes[i].Cover.Slice() {
+			names = append(names, e.Name)
+		}
+		names = append(names, assigned[i]...)
+
+		var rel *Relation
+		for _, name := range lib.RemoveDuplicates(names) {
+			r, ok := db[name]
+			if !ok {
+				return joinTree{}, fmt.Errorf("no relation for edge %v", name)
+			}
+			if rel == nil {
+				rel = &r
+			} else {
+				joined := rel.Join(r)
+				rel = &joined
+			}
+		}
+
+		t.rels = append(t.rels, rel.Project(t.nodes[i].Bag))
+	}
+
+	return t, nil
+}
+
+// reduceUp performs the bottom-up semi-join pass, after which the root relation is empty iff the query has no answer
+func (t *joinTree) reduceUp() {
+	for i := len(t.nodes) - 1; i > 0; i-- { // reverse pre-order visits children before parents
+		p := t.parent[i]
+		t.rels[p] = t.rels[p].Semijoin(t.rels[i])
+	}
+}
+
+// reduceDown performs the top-down semi-join pass, after which every remaining tuple is part of some answer
+func (t *joinTree) reduceDown() {
+	for i := 1; i < len(t.nodes); i++ {
+		t.rels[i] = t.rels[i].Semijoin(t.rels[t.parent[i]])
+	}
+}
+
+// Decide determines whether the query described by the graph has any answer over the database, using the bottom-up
+// semi-join pass over the decomposition
+func Decide(d lib.Decomp, g lib.Graph, db Database) (bool, error) {
+	t, err := bagRelations(d, g, db)
+	if err != nil {
+		return false, err
+	}
+
+	t.reduceUp()
+
+	return t.rels[0].Len() > 0, nil
+}
+
+// Evaluate computes the answers of the query described by the graph over the database, projected to the given
+// output vertices. If output is empty, all vertices of the graph are returned.
+func Evaluate(d lib.Decomp, g lib.Graph, db Database, output []int) (Relation, error) {
+	t, err := bagRelations(d, g, db)
+	if err != nil {
+		return Relation{}, err
+	}
+	if len(output) == 0 {
+		output = g.Vertices()
+	}
+
+	t.reduceUp()
+	t.reduceDown()
+
+	// join bottom-up, keeping only those attributes which are still needed above
+	for i := len(t.nodes) - 1; i > 0; i-- {
+		p := t.parent[i]
+		needed := append(append([]int{}, output...), t.nodes[p].Bag...)
+		t.rels[p] = t.rels[p].Join(t.rels[i].Project(needed))
+	}
+
+	return t.rels[0].Project(output), nil
+}
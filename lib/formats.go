@@ -0,0 +1,369 @@
+package lib
+
+// formats.go keeps a registry of the formats in which hypergraphs can be read and written, so that they can be
+// converted from one into another
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+)
+
+// A Format reads and writes hypergraphs in one representation
+type Format struct {
+	Name        string
+	Description string
+	Numeric     bool                                             // if set, vertices and edges are only known by numbers
+	Read        func(s string) (lib.Graph, Encoding, error)      // parses a single hypergraph
+	Write       func(w io.Writer, g lib.Graph, e Encoding) error // writes a single hypergraph, using the names of e
+	Split       func(s string) []string                          // splits an input of several hypergraphs, if possible
+}
+
+var formats []Format
+
+// RegisterFormat adds a format to the registry, replacing any earlier format of the same name
+func RegisterFormat(f Format) {
+	for i := range formats {
+		if formats[i].Name == f.Name {
+			formats[i] = f
+			return
+		}
+	}
+	formats = append(formats, f)
+}
+
+// FormatNames lists the names of the registered formats, in the order they were registered
+func FormatNames() []string {
+	var output []string
+	for _, f := range formats {
+		output = append(output, f.Name)
+	}
+	return output
+}
+
+// GetFormat returns the registered format of the given name
+func GetFormat(name string) (Format, error) {
+	for _, f := range formats {
+		if f.Name == strings.ToLower(name) {
+			return f, nil
+		}
+	}
+	return Format{}, fmt.Errorf("unknown format %q, expected one of %v", name, strings.Join(FormatNames(), ", "))
+}
+
+func init() {
+	RegisterFormat(Format{
+		Name:        "hyperbench",
+		Description: "HyperBench format, as in E1(a,b),E2(b,c).",
+		Read:        GetGraph,
+		Write: func(w io.Writer, g lib.Graph, e Encoding) error {
+			_, err := io.WriteString(w, e.HyperBench(g))
+			return err
+		},
+		Split: func(s string) []string { return SplitDocuments(s, false) },
+	})
+	RegisterFormat(Format{
+		Name:        "pace",
+		Description: "PACE 2019 format for hypergraphs, see pacechallenge.org/2019/htd/htd_format/",
+		Numeric:     true,
+		Read:        GetGraphPACE,
+		Write:       WritePACE,
+		Split:       func(s string) []string { return SplitDocuments(s, true) },
+	})
+	RegisterFormat(Format{
+		Name:        "gr",
+		Description: "PACE 2017 format for graphs, see pacechallenge.org/2017/treewidth/",
+		Numeric:     true,
+		Read:        GetGraphGR,
+		Write:       WriteGR,
+		Split:       func(s string) []string { return SplitDocuments(s, true) },
+	})
+	RegisterFormat(Format{
+		Name:        "json",
+		Description: "JSON object {\"edges\": [{\"name\": \"E1\", \"vertices\": [\"a\", \"b\"]}, ...]}",
+		Read:        GetGraphJSON,
+		Write:       WriteJSON,
+	})
+}
+
+// plainName matches the names which can be used in HyperBench format without quotes
+var plainName = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.;:!?\\/=\[\]'$<>+~@*-]*$`)
+
+// hyperBenchName quotes a name if needed to use it in HyperBench format
+func hyperBenchName(name string) string {
+	if plainName.MatchString(name) || (len(name) > 1 && strings.HasPrefix(name, "\"") && strings.HasSuffix(name, "\"")) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// plainNameOf removes the quotes of a name quoted in HyperBench format
+func plainNameOf(name string) string {
+	if unquoted, err := strconv.Unquote(name); err == nil && strings.HasPrefix(name, "\"") {
+		return unquoted
+	}
+	return name
+}
+
+// nameNumber returns the positive number i of a name "<prefix><i>", written without leading zeros, so that the
+// name can be restored from the number
+func nameNumber(name string, prefix string) (int, bool) {
+	i, err := strconv.Atoi(strings.TrimPrefix(name, prefix))
+	if !strings.HasPrefix(name, prefix) || err != nil || i < 1 || name != prefix+strconv.Itoa(i) {
+		return 0, false
+	}
+	return i, true
+}
+
+// edgeNumbers assigns to each edge its number in the PACE 2019 format. Edges named "E<j>", as produced by
+// GetGraphPACE, keep their number j if these are exactly the numbers 1 to m. Otherwise, all edges are numbered
+// in order.
+func edgeNumbers(g lib.Graph, encoding Encoding) (map[int]int, bool) {
+	output := make(map[int]int)
+	used := make(map[int]bool)
+
+	original := true
+	for _, e := range g.Edges.Slice() {
+		j, ok := nameNumber(encoding.Name(e.Name), "E")
+		if !ok || j > g.Edges.Len() || used[j] {
+			original = false
+			break
+		}
+		output[e.Name] = j
+		used[j] = true
+	}
+	if original {
+		return output, true
+	}
+
+	for j, e := range g.Edges.Slice() {
+		output[e.Name] = j + 1
+	}
+	return output, false
+}
+
+// NameMap lists the numbers which the vertices and edges of g get in the numeric formats under the encoding,
+// together with their names in the original encoding, one "vertex <number> <name>" or "edge <number> <name>" per
+// line. Passing the same encoding twice lists the names lost when writing a numeric format.
+func NameMap(g lib.Graph, encoding Encoding, original Encoding) []string {
+	var output []string
+
+	vertices, _ := vertexNumbers(g, encoding)
+	sorted := append([]int{}, g.Vertices()...)
+	sort.Slice(sorted, func(i, j int) bool { return vertices[sorted[i]] < vertices[sorted[j]] })
+	for _, v := range sorted {
+		output = append(output, fmt.Sprintf("vertex %d %s", vertices[v], plainNameOf(original.Name(v))))
+	}
+
+	edges, _ := edgeNumbers(g, encoding)
+	sortedEdges := append([]lib.Edge{}, g.Edges.Slice()...)
+	sort.Slice(sortedEdges, func(i, j int) bool { return edges[sortedEdges[i].Name] < edges[sortedEdges[j].Name] })
+	for _, e := range sortedEdges {
+		output = append(output, fmt.Sprintf("edge %d %s", edges[e.Name], plainNameOf(original.Name(e.Name))))
+	}
+
+	return output
+}
+
+// WritePACE writes g in PACE 2019 format. If the names of g don't come from this format, vertices and edges are
+// renumbered, and comments list the original name of each number.
+func WritePACE(w io.Writer, g lib.Graph, encoding Encoding) error {
+	vertices, originalVertices := vertexNumbers(g, encoding)
+	edges, originalEdges := edgeNumbers(g, encoding)
+	writer := bufio.NewWriter(w)
+
+	if !originalVertices || !originalEdges {
+		for _, line := range NameMap(g, encoding, encoding) {
+			fmt.Fprintln(writer, "c", line)
+		}
+	}
+
+	numVertices := 0
+	for _, i := range vertices {
+		if i > numVertices {
+			numVertices = i
+		}
+	}
+	fmt.Fprintf(writer, "p htd %d %d\n", numVertices, g.Edges.Len())
+
+	for _, e := range g.Edges.Slice() {
+		fmt.Fprintf(writer, "%d", edges[e.Name])
+		for _, v := range e.Vertices {
+			fmt.Fprintf(writer, " %d", vertices[v])
+		}
+		fmt.Fprintln(writer)
+	}
+
+	return writer.Flush()
+}
+
+// WriteGR writes g in the .gr format of PACE 2017, which only holds graphs: edges of two vertices become edges of
+// the graph, unary edges are dropped as the vertex is kept anyway, and larger edges are rejected. If the vertices
+// of g don't come from this format, they are renumbered, and comments list the original name of each number.
+// The names of edges are lost.
+func WriteGR(w io.Writer, g lib.Graph, encoding Encoding) error {
+	vertices, original := vertexNumbers(g, encoding)
+
+	var lines [][2]int
+	for _, e := range g.Edges.Slice() {
+		switch {
+		case len(e.Vertices) > 2:
+			return fmt.Errorf("edge %v has %d vertices, but the gr format only holds graphs",
+				plainNameOf(encoding.Name(e.Name)), len(e.Vertices))
+		case len(e.Vertices) == 2:
+			lines = append(lines, [2]int{vertices[e.Vertices[0]], vertices[e.Vertices[1]]})
+		}
+	}
+
+	writer := bufio.NewWriter(w)
+	if !original {
+		sorted := append([]int{}, g.Vertices()...)
+		sort.Slice(sorted, func(i, j int) bool { return vertices[sorted[i]] < vertices[sorted[j]] })
+		for _, v := range sorted {
+			fmt.Fprintf(writer, "c vertex %d %s\n", vertices[v], plainNameOf(encoding.Name(v)))
+		}
+	}
+
+	numVertices := 0
+	for _, i := range vertices {
+		if i > numVertices {
+			numVertices = i
+		}
+	}
+	fmt.Fprintf(writer, "p tw %d %d\n", numVertices, len(lines))
+	for _, l := range lines {
+		fmt.Fprintf(writer, "%d %d\n", l[0], l[1])
+	}
+
+	return writer.Flush()
+}
+
+// jsonGraph is the JSON format of hypergraphs, listing the edges with their names and vertices
+type jsonGraph struct {
+	Edges []jsonEdge `json:"edges"`
+}
+
+type jsonEdge struct {
+	Name     string   `json:"name"`
+	Vertices []string `json:"vertices"`
+}
+
+// GetGraphJSON parses a hypergraph in JSON format, and returns the encoding of the names used. Names which can't
+// be used in HyperBench format are kept in quotes in the encoding.
+func GetGraphJSON(s string) (lib.Graph, Encoding, error) {
+	var parsed jsonGraph
+	decoder := json.NewDecoder(strings.NewReader(s))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&parsed); err != nil {
+		return lib.Graph{}, Encoding{}, fmt.Errorf("couldn't parse input: %v", err)
+	}
+	if len(parsed.Edges) == 0 {
+		return lib.Graph{}, Encoding{}, fmt.Errorf("graph has no edges")
+	}
+
+	var buffer bytes.Buffer
+	for i, e := range parsed.Edges {
+		if e.Name == "" || len(e.Vertices) == 0 {
+			return lib.Graph{}, Encoding{}, fmt.Errorf("edge %d needs a name and vertices", i+1)
+		}
+
+		var vertices []string
+		for _, v := range e.Vertices {
+			if v == "" {
+				return lib.Graph{}, Encoding{}, fmt.Errorf("edge %v has a vertex without name", e.Name)
+			}
+			vertices = append(vertices, hyperBenchName(v))
+		}
+		if i > 0 {
+			buffer.WriteString(",\n")
+		}
+		buffer.WriteString(hyperBenchName(e.Name) + "(" + strings.Join(vertices, ",") + ")")
+	}
+	buffer.WriteString(".")
+
+	return GetGraph(buffer.String())
+}
+
+// WriteJSON writes g in JSON format, using the names of the encoding
+func WriteJSON(w io.Writer, g lib.Graph, encoding Encoding) error {
+	output := jsonGraph{Edges: []jsonEdge{}}
+
+	for _, e := range g.Edges.Slice() {
+		edge := jsonEdge{Name: plainNameOf(encoding.Name(e.Name)), Vertices: []string{}}
+		for _, v := range e.Vertices {
+			edge.Vertices = append(edge.Vertices, plainNameOf(encoding.Name(v)))
+		}
+		output.Edges = append(output.Edges, edge)
+	}
+
+	out, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
+
+// compareNames orders names such as "E2" before "E10", comparing a common prefix followed by numbers by the
+// value of the numbers
+func compareNames(a, b string) bool {
+	prefixA := strings.TrimRight(a, "0123456789")
+	prefixB := strings.TrimRight(b, "0123456789")
+
+	if prefixA == prefixB && len(prefixA) < len(a) && len(prefixB) < len(b) {
+		i, errA := strconv.Atoi(a[len(prefixA):])
+		j, errB := strconv.Atoi(b[len(prefixB):])
+		if errA == nil && errB == nil && i != j {
+			return i < j
+		}
+	}
+	return a < b
+}
+
+// SortGraph orders the edges of g by their names, and the vertices of each edge by theirs, so that equal
+// hypergraphs are written the same way. The graph g is not modified.
+func SortGraph(g lib.Graph, encoding Encoding) lib.Graph {
+	var edges []lib.Edge
+
+	for _, e := range g.Edges.Slice() {
+		vertices := append([]int{}, e.Vertices...)
+		sort.SliceStable(vertices, func(i, j int) bool {
+			return compareNames(plainNameOf(encoding.Name(vertices[i])), plainNameOf(encoding.Name(vertices[j])))
+		})
+		edges = append(edges, lib.Edge{Name: e.Name, Vertices: vertices})
+	}
+	sort.SliceStable(edges, func(i, j int) bool {
+		return compareNames(plainNameOf(encoding.Name(edges[i].Name)), plainNameOf(encoding.Name(edges[j].Name)))
+	})
+
+	return lib.Graph{Edges: lib.NewEdges(edges)}
+}
+
+// Renumber names the edges of g "E<j>" and its vertices "V<i>", both numbered from 1 in order of appearance,
+// matching the names used for the PACE formats. NameMap lists the names replaced.
+func Renumber(g lib.Graph) Encoding {
+	ids := make(map[string]int)
+	seen := make(map[int]bool)
+
+	for j, e := range g.Edges.Slice() {
+		ids["E"+strconv.Itoa(j+1)] = e.Name
+	}
+	for _, e := range g.Edges.Slice() {
+		for _, v := range e.Vertices {
+			if !seen[v] {
+				seen[v] = true
+				ids["V"+strconv.Itoa(len(seen))] = v
+			}
+		}
+	}
+
+	return NewEncoding(ids)
+}
@@ -0,0 +1,286 @@
+package lib
+
+// generate.go produces seeded random and structured hypergraphs, some with a known width, to be used as stress
+// instances with a known ground truth
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+)
+
+// Generated is a hypergraph produced by one of the generators. Vertices are numbered from 1, and named "V<n>",
+// while edges are named "E<n>" in order, matching the names used for the PACE format.
+type Generated struct {
+	Description string
+	NumVertices int
+	Edges       [][]int
+	TreeWidth   int // the treewidth of the primal graph, or -1 if unknown
+	HyperWidth  int // the hypertree width, or -1 if unknown
+}
+
+// comments lists what is known about the hypergraph, to be written as comments of the output
+func (g Generated) comments() []string {
+	output := []string{g.Description}
+
+	if g.TreeWidth >= 0 {
+		output = append(output, "treewidth "+strconv.Itoa(g.TreeWidth))
+	}
+	if g.HyperWidth >= 0 {
+		output = append(output, "hypertree width "+strconv.Itoa(g.HyperWidth))
+	}
+
+	return output
+}
+
+// HyperBench writes the hypergraph in HyperBench format, with what is known about it in comments
+func (g Generated) HyperBench() string {
+	var buffer bytes.Buffer
+
+	for _, c := range g.comments() {
+		buffer.WriteString("% " + c + "\n")
+	}
+	for i, e := range g.Edges {
+		buffer.WriteString("E" + strconv.Itoa(i+1) + "(")
+		for j, v := range e {
+			if j > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString("V" + strconv.Itoa(v))
+		}
+		buffer.WriteString(")")
+		if i != len(g.Edges)-1 {
+			buffer.WriteString(",\n")
+		}
+	}
+	buffer.WriteString(".\n")
+
+	return buffer.String()
+}
+
+// PACE writes the hypergraph in PACE 2019 format, with what is known about it in comments
+func (g Generated) PACE() string {
+	var buffer bytes.Buffer
+
+	for _, c := range g.comments() {
+		buffer.WriteString("c " + c + "\n")
+	}
+	buffer.WriteString(fmt.Sprintf("p htd %d %d\n", g.NumVertices, len(g.Edges)))
+	for i, e := range g.Edges {
+		buffer.WriteString(strconv.Itoa(i + 1))
+		for _, v := range e {
+			buffer.WriteString(" " + strconv.Itoa(v))
+		}
+		buffer.WriteString("\n")
+	}
+
+	return buffer.String()
+}
+
+// Graph parses the hypergraph, returning it together with the encoding of its names
+func (g Generated) Graph() (lib.Graph, Encoding, error) {
+	return GetGraph(g.HyperBench())
+}
+
+// randomSubset picks size distinct vertices out of 1 to n, in increasing order
+func randomSubset(r *rand.Rand, n, size int) []int {
+	output := r.Perm(n)[:size]
+	for i := range output {
+		output[i]++
+	}
+	sort.Ints(output)
+
+	return output
+}
+
+// RandomHypergraph produces m edges over n vertices, each made of a uniformly chosen set of vertices, whose size
+// is chosen uniformly between 1 and arity
+func RandomHypergraph(r *rand.Rand, n, m, arity int) (Generated, error) {
+	if n < 1 || m < 1 || arity < 1 {
+		return Generated{}, fmt.Errorf("random hypergraph needs at least one vertex and edge, and arity at least 1")
+	}
+	if arity > n {
+		arity = n
+	}
+
+	g := Generated{
+		Description: fmt.Sprintf("random hypergraph, %d vertices, %d edges, arity up to %d", n, m, arity),
+		NumVertices: n,
+		TreeWidth:   -1,
+		HyperWidth:  -1,
+	}
+	for i := 0; i < m; i++ {
+		g.Edges = append(g.Edges, randomSubset(r, n, r.Intn(arity)+1))
+	}
+
+	return g, nil
+}
+
+// Grid produces the grid graph with the given number of rows and columns, using binary edges. Its treewidth is
+// the smaller of the two, unless that is 1, for a path.
+func Grid(rows, cols int) (Generated, error) {
+	if rows < 1 || cols < 1 || rows*cols < 2 {
+		return Generated{}, fmt.Errorf("grid needs at least two vertices")
+	}
+
+	g := Generated{
+		Description: fmt.Sprintf("grid, %d x %d", rows, cols),
+		NumVertices: rows * cols,
+		TreeWidth:   rows,
+		HyperWidth:  -1,
+	}
+	if cols < rows {
+		g.TreeWidth = cols
+	}
+	if g.TreeWidth == 1 {
+		g.HyperWidth = 1 // a path
+	}
+
+	vertex := func(i, j int) int { return i*cols + j + 1 }
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			if j+1 < cols {
+				g.Edges = append(g.Edges, []int{vertex(i, j), vertex(i, j+1)})
+			}
+			if i+1 < rows {
+				g.Edges = append(g.Edges, []int{vertex(i, j), vertex(i+1, j)})
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// Cycle produces the cycle of length n, using binary edges, with treewidth and hypertree width 2
+func Cycle(n int) (Generated, error) {
+	if n < 3 {
+		return Generated{}, fmt.Errorf("cycle needs at least three vertices")
+	}
+
+	g := Generated{
+		Description: fmt.Sprintf("cycle, %d vertices", n),
+		NumVertices: n,
+		TreeWidth:   2,
+		HyperWidth:  2,
+	}
+	for i := 1; i <= n; i++ {
+		g.Edges = append(g.Edges, []int{i, i%n + 1})
+	}
+
+	return g, nil
+}
+
+// Clique produces the complete graph on n vertices, using binary edges, with treewidth n-1 and hypertree width
+// n/2, rounded up
+func Clique(n int) (Generated, error) {
+	if n < 2 {
+		return Generated{}, fmt.Errorf("clique needs at least two vertices")
+	}
+
+	g := Generated{
+		Description: fmt.Sprintf("clique, %d vertices", n),
+		NumVertices: n,
+		TreeWidth:   n - 1,
+		HyperWidth:  (n + 1) / 2,
+	}
+	for i := 1; i <= n; i++ {
+		for j := i + 1; j <= n; j++ {
+			g.Edges = append(g.Edges, []int{i, j})
+		}
+	}
+
+	return g, nil
+}
+
+// KTree produces a random k-tree on n vertices, using binary edges: starting from a clique of k+1 vertices, each
+// further vertex is connected to all vertices of a randomly chosen clique of k vertices. Its treewidth is k.
+func KTree(r *rand.Rand, n, k int) (Generated, error) {
+	if k < 1 || n < k+1 {
+		return Generated{}, fmt.Errorf("k-tree needs k at least 1, and at least k+1 vertices")
+	}
+
+	g, _ := Clique(k + 1)
+	g.Description = fmt.Sprintf("random %d-tree, %d vertices", k, n)
+	g.NumVertices = n
+	g.TreeWidth = k
+	g.HyperWidth = -1
+	if k == 1 {
+		g.HyperWidth = 1 // a tree
+	}
+
+	// the cliques of size k, to which new vertices can be attached
+	var cliques [][]int
+	for leave := 1; leave <= k+1; leave++ {
+		var clique []int
+		for v := 1; v <= k+1; v++ {
+			if v != leave {
+				clique = append(clique, v)
+			}
+		}
+		cliques = append(cliques, clique)
+	}
+
+	for v := k + 2; v <= n; v++ {
+		clique := cliques[r.Intn(len(cliques))]
+		for _, u := range clique {
+			g.Edges = append(g.Edges, []int{u, v})
+		}
+		for leave := range clique {
+			newClique := append(append([]int{}, clique[:leave]...), clique[leave+1:]...)
+			cliques = append(cliques, append(newClique, v))
+		}
+	}
+
+	return g, nil
+}
+
+// RandomQuery produces a random pattern resembling a conjunctive query, made of atoms with between 1 and arity
+// variables, out of at most the given number of variables. Each atom after the first joins with an earlier one on
+// at least one variable, so the pattern is connected, while its remaining variables are fresh or reused at random.
+func RandomQuery(r *rand.Rand, atoms, arity, variables int) (Generated, error) {
+	if atoms < 1 || arity < 1 || variables < arity {
+		return Generated{}, fmt.Errorf("query needs at least one atom, arity at least 1, and as many variables")
+	}
+
+	g := Generated{
+		Description: fmt.Sprintf("random query, %d atoms, arity up to %d, up to %d variables", atoms, arity,
+			variables),
+		TreeWidth:  -1,
+		HyperWidth: -1,
+	}
+
+	for i := 0; i < atoms; i++ {
+		size := r.Intn(arity) + 1
+		var atom []int
+		used := make(map[int]bool)
+
+		add := func(v int) {
+			if !used[v] {
+				used[v] = true
+				atom = append(atom, v)
+			}
+		}
+		if i > 0 {
+			add(r.Intn(g.NumVertices) + 1) // the join with an earlier atom
+		}
+		for len(atom) < size {
+			if g.NumVertices < variables && (g.NumVertices == 0 || r.Intn(2) == 0) {
+				g.NumVertices++
+				add(g.NumVertices)
+			} else if g.NumVertices > len(atom) {
+				add(r.Intn(g.NumVertices) + 1)
+			} else {
+				break // no variables left to use
+			}
+		}
+
+		sort.Ints(atom)
+		g.Edges = append(g.Edges, atom)
+	}
+
+	return g, nil
+}
@@ -1,4 +1,4 @@
-package main
+package lib
 
 // Hybrid algorithm of log-k-decomp and det-k-decomp.
 
@@ -6,11 +6,9 @@ import (
 	"fmt"
 	"log"
 	"reflect"
-	"runtime"
 
 	"github.com/cem-okulmus/BalancedGo/lib"
 	"github.com/cem-okulmus/disjoint"
-	logk "github.com/cem-okulmus/log-k-decomp/lib"
 )
 
 // HybridPredicate is used to determine when to switch from LogKDecomp to using DetKDecomp
@@ -22,12 +20,24 @@ type recursiveCall = func(H lib.Graph, Conn []int, allwowed lib.Edges, recDepth
 type LogKHybrid struct {
 	Graph     lib.Graph
 	K         int
-	cache     lib.Cache
+	cache     countingCache
+	counter   searchCounter
+	pool      workerPool // bounds the goroutines of the DetKDecomp searches subgraphs are handed over to
 	BalFactor int
 	Predicate HybridPredicate // used to determine when to switch to DetK
 	Size      int
 	level     int // keep track of
 	Generator lib.SearchGenerator
+	Weights   Weights   // if set, cheaper separators are tried first
+	CostBound float64   // if positive, the maximal cost of any node
+	Stop      *StopFlag // if set, the search gives up once it is stopped
+	Balance   Balance   // if set, replaces BalFactor in deciding which separators are balanced
+	Parallel  bool      // if set, DetKDecomp explores covers and components concurrently
+	// Memory, if set, empties the cache and switches to DetKDecomp when memory runs low, and stops the search once
+	// the limit is exceeded
+	Memory *MemoryGuard
+	// Workers, if positive, is the number of workers searching for separators concurrently, instead of one per CPU
+	Workers int
 }
 
 // SetGenerator defines the type of Search to use
@@ -104,14 +114,34 @@ func (l *LogKHybrid) SetWidth(K int) {
 	l.K = K
 }
 
+// SetCost sets the weights of the edges and the bound on the cost of nodes
+func (l *LogKHybrid) SetCost(weights Weights, bound float64) {
+	l.cache.Reset() // the bound might invalidate any old results
+
+	l.Weights = weights
+	l.CostBound = bound
+}
+
 // Name returns the name of the algorithm
 func (l *LogKHybrid) Name() string {
 	return "LogKHybrid"
 }
 
+// CacheStats reports how the searches of the algorithm used its cache
+func (l *LogKHybrid) CacheStats() CacheStats {
+	return l.cache.Stats()
+}
+
+// SearchStats reports the goroutines started by the searches of the algorithm, and the subgraphs handed over to
+// DetKDecomp
+func (l *LogKHybrid) SearchStats() SearchStats {
+	return l.counter.Stats()
+}
+
 // FindDecomp finds a decomp
 func (l *LogKHybrid) FindDecomp() lib.Decomp {
 	l.cache.Init()
+	l.counter.Init()
 
 	return l.findDecomp(l.Graph, []int{}, l.Graph.Edges, 0)
 }
@@ -123,10 +153,20 @@ func (l *LogKHybrid) FindDecompGraph(Graph lib.Graph) lib.Decomp {
 }
 
 func (l *LogKHybrid) detKWrapper(H lib.Graph, Conn []int, allwowed lib.Edges, recDepth int) lib.Decomp {
-	det := DetKDecomp{K: l.K, Graph: lib.Graph{Edges: allwowed}, BalFactor: l.BalFactor, SubEdge: false}
+	det := DetKDecomp{K: l.K, Graph: lib.Graph{Edges: allwowed}, BalFactor: l.BalFactor, SubEdge: false,
+		Weights: l.Weights, CostBound: l.CostBound, Stop: l.Stop, Parallel: l.Parallel, Memory: l.Memory,
+		Workers: l.Workers}
 
 	l.cache.CopyRef(&det.cache) // reuse the same cache as log-k
-	return det.findDecomp(H, Conn, recDepth)
+	l.counter.Share(&det.counter)
+	l.counter.DetKCall()
+	det.pool = &l.pool
+	return det.findDecomp(H, Conn, recDepth, det.Stop)
+}
+
+// stopped checks if the search should give up, as it was stopped or the memory limit exceeded
+func (l *LogKHybrid) stopped() bool {
+	return l.Stop.Stopped() || l.Memory.Exceeded()
 }
 
 // determine whether we have reached a (positive or negative) base case
@@ -161,7 +201,7 @@ func (l *LogKHybrid) baseCase(H lib.Graph, lenAE int) lib.Decomp {
 	}
 
 	// construct a decomp in the remaining two
-	if H.Edges.Len() <= l.K && len(H.Special) == 0 {
+	if H.Edges.Len() <= l.K && len(H.Special) == 0 && l.Weights.Allows(H.Edges, l.CostBound) {
 		output = lib.Decomp{Graph: H, Root: lib.Node{Bag: H.Vertices(), Cover: H.Edges}}
 	}
 	if H.Edges.Len() == 0 && len(H.Special) == 1 {
@@ -184,6 +224,10 @@ func (l *LogKHybrid) findDecomp(H lib.Graph, Conn []int, allowedFull lib.Edges,
 	if !lib.Subset(Conn, H.Vertices()) {
 		log.Panicln("Conn invariant violated.")
 	}
+	if l.stopped() {
+		return lib.Decomp{}
+	}
+	l.cache.Trim(l.Memory)
 
 	// Base Case
 	if l.baseCaseCheck(H.Edges.Len(), len(H.Special), allowedFull.Len()) {
@@ -193,29 +237,29 @@ func (l *LogKHybrid) findDecomp(H lib.Graph, Conn []int, allowedFull lib.Edges,
 	// Determine the function to use for the recursive calls
 	var recCall recursiveCall
 
-	if l.Predicate(H, l.K) {
+	if l.Predicate(H, l.K) || l.Memory.High() {
 		recCall = l.detKWrapper
 	} else {
 		recCall = l.findDecomp
 	}
 
 	//all vertices within (H ∪ Sp)
-	verticesH := append(H.Vertices())
+	verticesH := H.Vertices()
 
-	allowed := lib.FilterVertices(allowedFull, verticesH)
+	allowed := l.Weights.Sort(lib.FilterVertices(allowedFull, verticesH))
 
 	// Set up iterator for child
 
-	genChild := lib.SplitCombin(allowed.Len(), l.K, runtime.GOMAXPROCS(-1), false)
+	genChild := lib.SplitCombin(allowed.Len(), l.K, l.Memory.Workers(l.Workers), false)
 	parallelSearch := l.Generator.GetSearch(&H, &allowed, l.BalFactor, genChild)
 	// parallelSearch := lib.Search{H: &H, Edges: &allowed, BalFactor: l.BalFactor, Generators: genChild}
-	pred := lib.BalancedCheck{}
+	pred := l.Weights.WithCost(BalancedCheck{Balance: l.Balance}, l.CostBound)
 	parallelSearch.FindNext(pred) // initial Search
 	var Vertices = make(map[int]*disjoint.Element)
 
 	// checks all possibles nodes in H, together with PARENT loops, it covers all parent-child pairings
 CHILD:
-	for ; !parallelSearch.SearchEnded(); parallelSearch.FindNext(pred) {
+	for ; !parallelSearch.SearchEnded() && !l.stopped(); parallelSearch.FindNext(pred) {
 
 		childλ := lib.GetSubset(allowed, parallelSearch.GetResult())
 		compsε, _, _ := H.GetComponents(childλ, Vertices)
@@ -259,14 +303,15 @@ CHILD:
 		}
 
 		allowedParent := lib.FilterVertices(allowed, append(Conn, childλ.Vertices()...))
-		genParent := lib.SplitCombin(allowedParent.Len(), l.K, runtime.GOMAXPROCS(-1), false)
+		genParent := lib.SplitCombin(allowedParent.Len(), l.K, l.Memory.Workers(l.Workers), false)
 		parentalSearch := l.Generator.GetSearch(&H, &allowedParent, l.BalFactor, genParent)
 		// parentalSearch := lib.Search{H: &H, Edges: &allowedParent, BalFactor: l.BalFactor, Generators: genParent}
-		predPar := logk.ParentCheck{Conn: Conn, Child: childλ.Vertices()}
+		predPar := l.Weights.WithCost(ParentCheck{Conn: Conn, Child: childλ.Vertices(), Balance: l.Balance},
+			l.CostBound)
 		parentalSearch.FindNext(predPar)
 		// parentFound := false
 	PARENT:
-		for ; !parentalSearch.SearchEnded(); parentalSearch.FindNext(predPar) {
+		for ; !parentalSearch.SearchEnded() && !l.stopped(); parentalSearch.FindNext(predPar) {
 
 			parentλ := lib.GetSubset(allowedParent, parentalSearch.GetResult())
 			// log.Println("Looking at parent ", parentλ)
@@ -277,9 +322,11 @@ CHILD:
 			var compLowIndex int
 			var compLow lib.Graph
 
+			balance := l.Balance.OrFactor(l.BalFactor)
+
 			// Check if parent is un-balanced
 			for i := range compsπ {
-				if compsπ[i].Len() > H.Len()/2 {
+				if balance.TooLarge(H, compsπ[i], parentλ) {
 					foundLow = true
 					compLowIndex = i //keep track of the index for composing comp_up later
 					compLow = compsπ[i]
@@ -358,6 +405,7 @@ CHILD:
 				decompTemp := lib.Decomp{Graph: compUp, Root: lib.Node{Bag: lib.Inter(parentλ.Vertices(), verticesH),
 					Cover: parentλ, Children: []lib.Node{{Bag: childχ, Cover: childλ}}}}
 
+				l.counter.Goroutine()
 				go func(decomp lib.Decomp) {
 					chanUp <- decomp
 				}(decompTemp)
@@ -374,6 +422,7 @@ CHILD:
 				//Reducing the allowed edges
 				allowedReduced := allowedFull.Diff(compLow.Edges)
 
+				l.counter.Goroutine()
 				go func(comp_up lib.Graph, Conn []int, allowedReduced lib.Edges) {
 					chanUp <- recCall(comp_up, Conn, allowedReduced, recDepth)
 				}(compUp, Conn, allowedReduced)
@@ -386,6 +435,7 @@ CHILD:
 			for x := range compsε {
 				Connχ := lib.Inter(compsε[x].Vertices(), childχ)
 
+				l.counter.Goroutine()
 				go func(x int, comps_c []lib.Graph, Conn_x []int, allowedFull lib.Edges) {
 					var out decompInt
 					out.Decomp = recCall(comps_c[x], Conn_x, allowedFull, recDepth)
@@ -0,0 +1,132 @@
+package lib
+
+// input.go handles reading the input of the tool, which may come from a file or stdin, may be compressed, and
+// may contain several hypergraphs
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	magicGzip = []byte{0x1f, 0x8b}
+	magicZstd = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ReadInput reads the input at the given path, where "-" denotes stdin. Input compressed with gzip or zstd is
+// detected automatically and decompressed.
+func ReadInput(path string) ([]byte, error) {
+	var reader io.Reader
+
+	if path == "-" {
+		reader = os.Stdin
+	} else {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		reader = f
+	}
+
+	dat, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decompress(dat)
+}
+
+// Decompress detects gzip or zstd compressed data by its magic number and decompresses it. Any other data is
+// returned unchanged.
+func Decompress(dat []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(dat, magicGzip):
+		r, err := gzip.NewReader(bytes.NewReader(dat))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+
+	case bytes.HasPrefix(dat, magicZstd):
+		r, err := zstd.NewReader(bytes.NewReader(dat))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return ioutil.ReadAll(r)
+	}
+
+	return dat, nil
+}
+
+// stripComment removes a HyperBench comment from a line, to detect the end of a hypergraph
+func stripComment(line string) string {
+	if i := strings.Index(line, "%"); i >= 0 {
+		line = line[:i]
+	}
+	if i := strings.Index(line, "//"); i >= 0 {
+		line = line[:i]
+	}
+	return strings.TrimSpace(line)
+}
+
+// SplitDocuments splits an input holding several hypergraphs into one string per hypergraph. In HyperBench
+// format, a hypergraph ends with the "." after its last edge, while in PACE format, each hypergraph starts with
+// its own "p htd" header. Parts without any content are dropped.
+func SplitDocuments(input string, pace bool) []string {
+	var output []string
+	var current bytes.Buffer
+	hasContent := false
+
+	flush := func() {
+		if hasContent {
+			output = append(output, current.String())
+		}
+		current.Reset()
+		hasContent = false
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(input))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(input)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if pace {
+			fields := strings.Fields(line)
+			if len(fields) > 0 && fields[0] == "p" && hasContent {
+				flush()
+			}
+			if len(fields) > 0 && fields[0] != "c" {
+				hasContent = true
+			}
+			current.WriteString(line + "\n")
+			continue
+		}
+
+		stripped := stripComment(line)
+		if len(stripped) > 0 {
+			hasContent = true
+		}
+		current.WriteString(line + "\n")
+
+		if strings.HasSuffix(stripped, ").") || stripped == "." {
+			flush()
+		}
+	}
+	flush()
+
+	if len(output) == 0 { // leave it to the parser to complain about empty input
+		return []string{input}
+	}
+
+	return output
+}
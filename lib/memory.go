@@ -0,0 +1,167 @@
+package lib
+
+// memory.go watches the memory used by the process, so that searches can degrade gracefully on large instances
+// instead of being killed once they run out of memory
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// levels of memory use, as last measured by a MemoryGuard
+const (
+	memoryLow int32 = iota
+	memoryHigh
+	memoryExceeded
+)
+
+// A MemoryGuard compares the memory used by the process against a limit. Once the use is near the limit (at least
+// 4/5 of it), searches empty their caches, the number of workers of the searches using the guard is halved with each
+// further measurement still near the limit, and the log-k algorithms fall back to DetKDecomp. Once the limit is
+// exceeded, the searches are stopped, and the guard stays in that state, so each search should get a guard of its
+// own. A nil MemoryGuard never reports any pressure.
+type MemoryGuard struct {
+	Limit uint64 // in bytes
+
+	level     int32
+	peak      uint64
+	evictions int64 // number of times the caches were asked to empty
+	halvings  int32 // number of times the workers were halved
+	stop      StopFlag
+	done      chan struct{}
+	once      sync.Once
+}
+
+// NewMemoryGuard returns a guard for the limit, given in bytes. It only measures once started.
+func NewMemoryGuard(limit uint64) *MemoryGuard {
+	return &MemoryGuard{Limit: limit, done: make(chan struct{})}
+}
+
+// memoryUsed measures the memory in use by the heap and the stacks of goroutines
+func memoryUsed() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	return stats.HeapAlloc + stats.StackInuse
+}
+
+// Start measures the memory in use at the given interval, until the guard is closed. Measurements near the limit
+// are repeated after a garbage collection, so that memory no longer in use is not counted.
+func (m *MemoryGuard) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-m.done:
+				return
+			case <-ticker.C:
+				used := memoryUsed()
+				if used*5 >= m.Limit*4 {
+					runtime.GC()
+					used = memoryUsed()
+				}
+				m.Check(used)
+			}
+		}
+	}()
+}
+
+// Close stops measuring the memory
+func (m *MemoryGuard) Close() {
+	if m != nil {
+		m.once.Do(func() { close(m.done) })
+	}
+}
+
+// Check updates the guard with the memory currently in use, in bytes, and takes the actions needed for it. Once the
+// limit is exceeded, the guard stays in that state.
+func (m *MemoryGuard) Check(used uint64) {
+	for peak := atomic.LoadUint64(&m.peak); used > peak; peak = atomic.LoadUint64(&m.peak) {
+		if atomic.CompareAndSwapUint64(&m.peak, peak, used) {
+			break
+		}
+	}
+	if m.Exceeded() {
+		return
+	}
+
+	switch {
+	case used >= m.Limit:
+		atomic.StoreInt32(&m.level, memoryExceeded)
+		m.stop.Stop()
+	case used*5 >= m.Limit*4:
+		if atomic.SwapInt32(&m.level, memoryHigh) == memoryHigh { // emptying the caches was not enough
+			if atomic.LoadInt32(&m.halvings) < 30 { // beyond this, a single worker is left anyway
+				atomic.AddInt32(&m.halvings, 1)
+			}
+		}
+		atomic.AddInt64(&m.evictions, 1)
+	default:
+		atomic.StoreInt32(&m.level, memoryLow)
+	}
+}
+
+// High checks if the memory in use is near the limit, or exceeded it
+func (m *MemoryGuard) High() bool {
+	return m != nil && atomic.LoadInt32(&m.level) != memoryLow
+}
+
+// Exceeded checks if the memory in use exceeded the limit, after which searches are stopped
+func (m *MemoryGuard) Exceeded() bool {
+	return m != nil && atomic.LoadInt32(&m.level) == memoryExceeded
+}
+
+// Flag returns the flag which is stopped once the limit is exceeded, or nil for a nil guard
+func (m *MemoryGuard) Flag() *StopFlag {
+	if m == nil {
+		return nil
+	}
+	return &m.stop
+}
+
+// Workers scales down the number of workers a search would use without pressure, where zero stands for one per CPU,
+// halving it each time emptying the caches was not enough, but keeping at least one. A nil guard leaves the number
+// unchanged.
+func (m *MemoryGuard) Workers(n int) int {
+	if n <= 0 {
+		n = runtime.GOMAXPROCS(-1)
+	}
+	if m == nil {
+		return n
+	}
+	if n >>= atomic.LoadInt32(&m.halvings); n < 1 {
+		return 1
+	}
+	return n
+}
+
+// Evictions returns the number of times the caches were asked to empty so far
+func (m *MemoryGuard) Evictions() int64 {
+	if m == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&m.evictions)
+}
+
+// String reports the peak memory measured, and the actions taken to stay below the limit
+func (m *MemoryGuard) String() string {
+	const mb = 1 << 20
+
+	output := fmt.Sprintf("peak %d MB of %d MB", atomic.LoadUint64(&m.peak)/mb, m.Limit/mb)
+	if evictions := m.Evictions(); evictions > 0 {
+		output += fmt.Sprintf(", caches emptied %d times", evictions)
+	}
+	if halvings := atomic.LoadInt32(&m.halvings); halvings > 0 {
+		output += fmt.Sprintf(", workers halved %d times", halvings)
+	}
+	if m.Exceeded() {
+		output += ", limit exceeded"
+	}
+
+	return output
+}
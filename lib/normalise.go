@@ -0,0 +1,269 @@
+package lib
+
+// normalise.go implements a post-processing pass, which removes redundant nodes and edges from hypertree
+// decompositions and optionally chooses a different root, while keeping them valid HDs of the same width
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+)
+
+// NormaliseOptions select the steps of the normalisation
+type NormaliseOptions struct {
+	RemoveRedundant bool  // remove nodes whose bag is a subset of a neighbour's bag
+	MinimiseCovers  bool  // shrink each cover to an inclusion-minimal one
+	MinimiseDepth   bool  // choose the root to minimise the depth of the tree
+	RootVertices    []int // choose the root to contain as many of these vertices as possible
+}
+
+// A NormaliseReport lists the changes made by the normalisation
+type NormaliseReport struct {
+	RemovedNodes  int
+	ShrunkCovers  int // number of covers from which edges were removed
+	RemovedEdges  int
+	Rerooted      bool
+	OldDepth      int
+	NewDepth      int
+	MissingAtRoot []int // root vertices which couldn't be put into the bag of the root
+}
+
+func (r NormaliseReport) String() string {
+	var changes []string
+
+	if r.RemovedNodes > 0 {
+		changes = append(changes, fmt.Sprintf("removed %d redundant node(s)", r.RemovedNodes))
+	}
+	if r.RemovedEdges > 0 {
+		changes = append(changes, fmt.Sprintf("removed %d edge(s) from %d cover(s)", r.RemovedEdges, r.ShrunkCovers))
+	}
+	if r.Rerooted {
+		changes = append(changes, "chose a new root")
+	}
+	if r.OldDepth != r.NewDepth {
+		changes = append(changes, fmt.Sprintf("depth %d -> %d", r.OldDepth, r.NewDepth))
+	}
+	if len(r.MissingAtRoot) > 0 {
+		changes = append(changes, fmt.Sprintf("%d of the given vertices not at the root", len(r.MissingAtRoot)))
+	}
+
+	if len(changes) == 0 {
+		return "no changes"
+	}
+	return strings.Join(changes, ", ")
+}
+
+// subtreeVertices collects the vertices of all bags in the subtree rooted at n
+func subtreeVertices(n lib.Node) []int {
+	output := append([]int{}, n.Bag...)
+
+	for _, c := range n.Children {
+		output = append(output, subtreeVertices(c)...)
+	}
+
+	return lib.RemoveDuplicates(output)
+}
+
+// specialCondition checks that no vertex covered but not included in the bag of n occurs below n
+func specialCondition(n lib.Node) bool {
+	hidden := lib.Diff(n.Cover.Vertices(), n.Bag)
+
+	return len(lib.Inter(hidden, subtreeVertices(n))) == 0
+}
+
+// noSCViolation checks the special condition for all nodes of the subtree rooted at n
+func noSCViolation(n lib.Node) bool {
+	if !specialCondition(n) {
+		return false
+	}
+
+	for _, c := range n.Children {
+		if !noSCViolation(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// depth returns the length of the longest path from n to a leaf
+func depth(n lib.Node) int {
+	output := 0
+
+	for _, c := range n.Children {
+		if d := depth(c) + 1; d > output {
+			output = d
+		}
+	}
+
+	return output
+}
+
+// removeRedundant performs one pass over the subtree rooted at n, removing nodes whose bag is a subset of a
+// neighbour's bag. A node contained in its parent is always removed, with its children moved to the parent. A node
+// contained in a child is replaced by the child, as long as the special condition still holds for it.
+func removeRedundant(n lib.Node, report *NormaliseReport) lib.Node {
+	var children []lib.Node
+
+	for _, c := range n.Children {
+		c = removeRedundant(c, report)
+
+		if lib.Subset(c.Bag, n.Bag) {
+			children = append(children, c.Children...)
+			report.RemovedNodes++
+			continue
+		}
+		children = append(children, c)
+	}
+
+	for i, c := range children {
+		if !lib.Subset(n.Bag, c.Bag) {
+			continue
+		}
+
+		merged := lib.Node{Bag: c.Bag, Cover: c.Cover, Children: append([]lib.Node{}, c.Children...)}
+		merged.Children = append(merged.Children, children[:i]...)
+		merged.Children = append(merged.Children, children[i+1:]...)
+
+		if specialCondition(merged) {
+			report.RemovedNodes++
+			return merged
+		}
+	}
+
+	return lib.Node{Bag: n.Bag, Cover: n.Cover, Children: children}
+}
+
+// minimiseCovers removes edges from each cover of the subtree rooted at n, as long as the bag stays covered.
+// This can't violate the special condition, as it only shrinks the set of covered vertices.
+func minimiseCovers(n lib.Node, report *NormaliseReport) lib.Node {
+	cover := n.Cover.Slice()
+
+	removed := 0
+	for i := len(cover) - 1; i >= 0; i-- {
+		without := append(append([]lib.Edge{}, cover[:i]...), cover[i+1:]...)
+		edges := lib.NewEdges(without)
+		if lib.Subset(n.Bag, edges.Vertices()) {
+			cover = without
+			removed++
+		}
+	}
+	if removed > 0 {
+		report.ShrunkCovers++
+		report.RemovedEdges = report.RemovedEdges + removed
+	}
+
+	output := lib.Node{Bag: n.Bag, Cover: lib.NewEdges(cover)}
+	for _, c := range n.Children {
+		output.Children = append(output.Children, minimiseCovers(c, report))
+	}
+
+	return output
+}
+
+// reroot chooses a new root for the tree rooted at n, trying the candidates in the order of preference given by
+// less, and keeping the first one for which the special condition holds everywhere. The index 0 denotes the current
+// root. It returns whether a different root was chosen.
+func reroot(n lib.Node, less func(t joinTree, ecc []int, i, j int) bool) (lib.Node, bool) {
+	t := newJoinTree(lib.Decomp{Root: n})
+
+	neighbours := make([][]int, len(t.nodes))
+	for i := range t.nodes {
+		neighbours[i] = append(neighbours[i], t.children[i]...)
+		if t.parent[i] >= 0 {
+			neighbours[i] = append(neighbours[i], t.parent[i])
+		}
+	}
+
+	// the eccentricity of a node is the depth of the tree rooted at it
+	ecc := make([]int, len(t.nodes))
+	for i := range t.nodes {
+		var farthest func(j, from int) int
+		farthest = func(j, from int) int {
+			output := 0
+			for _, k := range neighbours[j] {
+				if k != from {
+					if d := farthest(k, j) + 1; d > output {
+						output = d
+					}
+				}
+			}
+			return output
+		}
+		ecc[i] = farthest(i, -1)
+	}
+
+	candidates := make([]int, len(t.nodes))
+	for i := range candidates {
+		candidates[i] = i
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return less(t, ecc, candidates[a], candidates[b])
+	})
+
+	var build func(j, from int) lib.Node
+	build = func(j, from int) lib.Node {
+		output := lib.Node{Bag: t.nodes[j].Bag, Cover: t.nodes[j].Cover}
+		for _, k := range neighbours[j] {
+			if k != from {
+				output.Children = append(output.Children, build(k, j))
+			}
+		}
+		return output
+	}
+
+	for _, i := range candidates {
+		if i == 0 {
+			return n, false // the current root is the best choice left
+		}
+		if root := build(i, -1); noSCViolation(root) {
+			return root, true
+		}
+	}
+
+	return n, false
+}
+
+// Normalise applies the selected steps to the decomposition, and reports the changes made. Node costs are not
+// kept, as covers may change.
+func Normalise(d lib.Decomp, opts NormaliseOptions) (lib.Decomp, NormaliseReport) {
+	var report NormaliseReport
+	report.OldDepth = depth(d.Root)
+
+	root := d.Root
+
+	if opts.RemoveRedundant {
+		for before := -1; before != report.RemovedNodes; { // repeat until nothing changes
+			before = report.RemovedNodes
+			root = removeRedundant(root, &report)
+		}
+	}
+
+	if opts.MinimiseCovers {
+		root = minimiseCovers(root, &report)
+	}
+
+	if opts.MinimiseDepth || len(opts.RootVertices) > 0 {
+		root, report.Rerooted = reroot(root, func(t joinTree, ecc []int, i, j int) bool {
+			if len(opts.RootVertices) > 0 {
+				inI := len(lib.Inter(t.nodes[i].Bag, opts.RootVertices))
+				inJ := len(lib.Inter(t.nodes[j].Bag, opts.RootVertices))
+				if inI != inJ {
+					return inI > inJ
+				}
+			}
+			if opts.MinimiseDepth {
+				return ecc[i] < ecc[j]
+			}
+			return false
+		})
+
+		report.MissingAtRoot = lib.Diff(opts.RootVertices, root.Bag)
+	}
+
+	report.NewDepth = depth(root)
+
+	return lib.Decomp{Graph: d.Graph, Root: root}, report
+}
@@ -8,8 +8,9 @@ import (
 // ParentCheck looks a separator that could function as the direct ancestor (or "parent")
 // of some child node in the GHD, where the connecting vertices "Conn" are explicitly provided
 type ParentCheck struct {
-	Conn  []int
-	Child []int
+	Conn    []int
+	Child   []int
+	Balance Balance // if not set, the balance factor of the search is used
 }
 
 // Check performs the needed computation to ensure whether sep is a good parent
@@ -21,10 +22,10 @@ func (p ParentCheck) Check(H *lib.Graph, sep *lib.Edges, balFactor int, Vertices
 	foundCompLow := false
 	var compLow lib.Graph
 
-	balancednessLimit := (((H.Len()) * (balFactor - 1)) / balFactor)
+	balance := p.Balance.OrFactor(balFactor)
 
 	for i := range comps {
-		if comps[i].Len() > balancednessLimit {
+		if balance.TooLarge(*H, comps[i], *sep) {
 			foundCompLow = true
 			compLow = comps[i]
 		}
@@ -0,0 +1,283 @@
+package lib
+
+// preprocess.go provides the reductions which can be combined into a preprocessing pipeline, each with a matching
+// step restoring decompositions of the reduced hypergraph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+)
+
+// A Reduction is the outcome of a preprocessing step, which turns a decomposition of the reduced hypergraph into
+// one of the hypergraph the step was applied to
+type Reduction interface {
+	Restore(root lib.Node) (lib.Node, bool)
+}
+
+// PreprocessingSteps lists the names of the steps which can be used in a pipeline. The step "hinge" doesn't change
+// the hypergraph, and is handled by the caller.
+var PreprocessingSteps = []string{"dedup", "subsume", "degree1", "typecollapse", "gyo", "hinge"}
+
+// ParsePipeline splits a comma-separated list of preprocessing steps, checking that each is known
+func ParsePipeline(list string) ([]string, error) {
+	var output []string
+
+	for _, step := range strings.Split(list, ",") {
+		step = strings.ToLower(strings.TrimSpace(step))
+		if step == "" {
+			continue
+		}
+
+		known := false
+		for _, s := range PreprocessingSteps {
+			known = known || s == step
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown preprocessing step %q, expected one of %v", step,
+				strings.Join(PreprocessingSteps, ", "))
+		}
+		output = append(output, step)
+	}
+
+	return output, nil
+}
+
+// Preprocess applies a single step of a pipeline to the hypergraph, returning the reduced hypergraph and the
+// reduction needed to restore its decompositions
+func Preprocess(step string, g lib.Graph) (lib.Graph, Reduction, error) {
+	switch step {
+	case "dedup":
+		return RemoveDuplicateEdges(g)
+	case "subsume":
+		return RemoveSubsumedEdges(g)
+	case "degree1":
+		return EliminateDegreeOne(g)
+	case "typecollapse":
+		reduced, removalMap, _ := g.TypeCollapse()
+		return reduced, typeReduction(removalMap), nil
+	case "gyo":
+		reduced, ops := g.GYÖReduct()
+		return reduced, gyöReduction(ops), nil
+	}
+
+	return g, nil, fmt.Errorf("%q is not a reduction of the hypergraph", step)
+}
+
+// typeReduction restores the vertices removed by the type collapse
+type typeReduction map[int][]int
+
+// Restore adds the removed vertices wherever the vertex of the same type occurs
+func (r typeReduction) Restore(root lib.Node) (lib.Node, bool) {
+	return root.RestoreTypes(r)
+}
+
+// gyöReduction restores the edges and vertices removed by the GYÖ reduct
+type gyöReduction []lib.GYÖReduct
+
+// Restore reverts the operations of the GYÖ reduct
+func (r gyöReduction) Restore(root lib.Node) (lib.Node, bool) {
+	return root.RestoreGYÖ(r)
+}
+
+// isEmpty checks if the node is the root of an empty decomposition
+func isEmpty(n lib.Node) bool {
+	return len(n.Bag) == 0 && n.Cover.Len() == 0 && len(n.Children) == 0
+}
+
+// attachLeaf adds a leaf covering the edge below the first node containing all its vertices, copying the nodes on
+// the path to it
+func attachLeaf(n lib.Node, e lib.Edge) (lib.Node, bool) {
+	leaf := lib.Node{Bag: e.Vertices, Cover: lib.NewEdges([]lib.Edge{e})}
+
+	if isEmpty(n) {
+		return leaf, true
+	}
+	if lib.Subset(e.Vertices, n.Bag) {
+		n.Children = append(append([]lib.Node{}, n.Children...), leaf)
+		return n, true
+	}
+
+	for i := range n.Children {
+		if child, ok := attachLeaf(n.Children[i], e); ok {
+			n.Children = append([]lib.Node{}, n.Children...)
+			n.Children[i] = child
+			return n, true
+		}
+	}
+
+	return n, false
+}
+
+// edgeReduction restores removed edges, whose vertices are all contained in some remaining edge
+type edgeReduction []lib.Edge
+
+// Restore adds a leaf for each removed edge, below a node containing its vertices
+func (r edgeReduction) Restore(root lib.Node) (lib.Node, bool) {
+	for i := len(r) - 1; i >= 0; i-- {
+		var ok bool
+		if root, ok = attachLeaf(root, r[i]); !ok {
+			return root, false
+		}
+	}
+
+	return root, true
+}
+
+// RemoveDuplicateEdges keeps only the first of several edges with the same set of vertices
+func RemoveDuplicateEdges(g lib.Graph) (lib.Graph, Reduction, error) {
+	var edges []lib.Edge
+	var removed edgeReduction
+	seen := make(map[string]bool)
+
+	for _, e := range g.Edges.Slice() {
+		vertices := lib.RemoveDuplicates(append([]int{}, e.Vertices...))
+		sort.Ints(vertices)
+
+		key := fmt.Sprint(vertices)
+		if seen[key] {
+			removed = append(removed, e)
+			continue
+		}
+		seen[key] = true
+		edges = append(edges, e)
+	}
+
+	return lib.Graph{Edges: lib.NewEdges(edges)}, removed, nil
+}
+
+// RemoveSubsumedEdges removes each edge whose vertices are a proper subset of those of another edge. Edges with
+// the same vertices are not affected, as they are handled by RemoveDuplicateEdges.
+func RemoveSubsumedEdges(g lib.Graph) (lib.Graph, Reduction, error) {
+	var edges []lib.Edge
+	var removed edgeReduction
+	all := g.Edges.Slice()
+
+	for i, e := range all {
+		subsumed := false
+		for j, f := range all {
+			if i != j && lib.Subset(e.Vertices, f.Vertices) && !lib.Subset(f.Vertices, e.Vertices) {
+				subsumed = true
+				break
+			}
+		}
+
+		if subsumed {
+			removed = append(removed, e)
+		} else {
+			edges = append(edges, e)
+		}
+	}
+
+	return lib.Graph{Edges: lib.NewEdges(edges)}, removed, nil
+}
+
+// degreeOp records an edge from which vertices of degree one were removed, keeping the rest
+type degreeOp struct {
+	edge lib.Edge
+	kept []int
+}
+
+// degreeReduction restores the vertices of degree one
+type degreeReduction []degreeOp
+
+// topmost finds a node containing all the vertices, none of whose ancestors does, and replaces it by f applied to it
+func topmost(n lib.Node, vertices []int, f func(lib.Node) lib.Node) (lib.Node, bool) {
+	if lib.Subset(vertices, n.Bag) {
+		return f(n), true
+	}
+
+	for i := range n.Children {
+		if child, ok := topmost(n.Children[i], vertices, f); ok {
+			n.Children = append([]lib.Node{}, n.Children...)
+			n.Children[i] = child
+			return n, true
+		}
+	}
+
+	return n, false
+}
+
+// replaceEdge puts the edge into each cover containing an edge of the same name
+func replaceEdge(n lib.Node, e lib.Edge) lib.Node {
+	cover := n.Cover.Slice()
+	for i := range cover {
+		if cover[i].Name == e.Name {
+			cover = append([]lib.Edge{}, cover...)
+			cover[i] = e
+			n.Cover = lib.NewEdges(cover)
+			break
+		}
+	}
+
+	if len(n.Children) > 0 {
+		children := make([]lib.Node, len(n.Children))
+		for i := range n.Children {
+			children[i] = replaceEdge(n.Children[i], e)
+		}
+		n.Children = children
+	}
+
+	return n
+}
+
+// Restore adds back the removed vertices of each edge. These are added to the topmost node containing the kept
+// vertices, if the edge is in its cover, and otherwise to a new leaf below it. By the special condition, no node
+// above it has the edge in its cover, so the condition still holds once the covers use the original edge.
+func (r degreeReduction) Restore(root lib.Node) (lib.Node, bool) {
+	for _, op := range r {
+		var ok bool
+		root, ok = topmost(root, op.kept, func(n lib.Node) lib.Node {
+			for _, f := range n.Cover.Slice() {
+				if f.Name == op.edge.Name {
+					n.Bag = lib.RemoveDuplicates(append(append([]int{}, n.Bag...), op.edge.Vertices...))
+					return n
+				}
+			}
+			leaf := lib.Node{Bag: op.edge.Vertices, Cover: lib.NewEdges([]lib.Edge{op.edge})}
+			n.Children = append(append([]lib.Node{}, n.Children...), leaf)
+			return n
+		})
+		if !ok {
+			return root, false
+		}
+		root = replaceEdge(root, op.edge)
+	}
+
+	return root, true
+}
+
+// EliminateDegreeOne removes the vertices occurring in only one edge. At least one vertex of each edge is kept,
+// so that no edge disappears.
+func EliminateDegreeOne(g lib.Graph) (lib.Graph, Reduction, error) {
+	degree := make(map[int]int)
+	for _, e := range g.Edges.Slice() {
+		for _, v := range lib.RemoveDuplicates(append([]int{}, e.Vertices...)) {
+			degree[v]++
+		}
+	}
+
+	var edges []lib.Edge
+	var ops degreeReduction
+	for _, e := range g.Edges.Slice() {
+		var kept []int
+		for _, v := range e.Vertices {
+			if degree[v] > 1 {
+				kept = append(kept, v)
+			}
+		}
+		if len(kept) == 0 && len(e.Vertices) > 0 {
+			kept = e.Vertices[:1]
+		}
+
+		if len(kept) < len(e.Vertices) {
+			ops = append(ops, degreeOp{edge: e, kept: kept})
+			e = lib.Edge{Name: e.Name, Vertices: kept}
+		}
+		edges = append(edges, e)
+	}
+
+	return lib.Graph{Edges: lib.NewEdges(edges)}, ops, nil
+}
@@ -0,0 +1,377 @@
+package lib
+
+// repair.go updates a hypertree decomposition after small changes to its hypergraph, decomposing only the part of
+// the tree affected by the changes again, and keeping the rest
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+)
+
+// A RepairReport describes how a decomposition was repaired
+type RepairReport struct {
+	Nodes      int  // nodes of the repaired decomposition
+	Reused     int  // nodes kept from the old decomposition
+	Replaced   int  // nodes of the old decomposition dropped or decomposed again
+	Attempts   int  // subtrees decomposed again, each one larger than the one before
+	FullSearch bool // if set, the repair failed, and the decomposition was searched for from scratch
+}
+
+// ReusedShare returns the share of the nodes of the repaired decomposition kept from the old one
+func (r RepairReport) ReusedShare() float64 {
+	if r.Nodes == 0 {
+		return 0
+	}
+	return float64(r.Reused) / float64(r.Nodes)
+}
+
+func (r RepairReport) String() string {
+	if r.FullSearch {
+		return fmt.Sprintf("repair failed after %d attempt(s), searched from scratch", r.Attempts)
+	}
+	return fmt.Sprintf("reused %d of %d node(s) (%.0f%%), replaced %d node(s) in %d attempt(s)", r.Reused, r.Nodes,
+		100*r.ReusedShare(), r.Replaced, r.Attempts)
+}
+
+// repairNode is a node of the old decomposition, with its position in the tree
+type repairNode struct {
+	node   lib.Node
+	path   []int // indices of the children leading from the root to the node
+	parent int   // index of the parent in the list of nodes, or -1 for the root
+}
+
+// flattenTree lists the nodes of the tree rooted at n in depth-first order, each after its parent
+func flattenTree(n lib.Node) []repairNode {
+	var output []repairNode
+
+	var visit func(n lib.Node, path []int, parent int)
+	visit = func(n lib.Node, path []int, parent int) {
+		index := len(output)
+		output = append(output, repairNode{node: n, path: path, parent: parent})
+		for i, c := range n.Children {
+			visit(c, append(append([]int{}, path...), i), index)
+		}
+	}
+	visit(n, []int{}, -1)
+
+	return output
+}
+
+// inSubtree checks if the node at the path lies in the subtree rooted at the node at the prefix
+func inSubtree(path []int, prefix []int) bool {
+	return len(path) >= len(prefix) && reflect.DeepEqual(path[:len(prefix)], prefix)
+}
+
+// commonPrefix returns the path of the lowest common ancestor of the nodes at the paths
+func commonPrefix(paths [][]int) []int {
+	output := paths[0]
+
+	for _, p := range paths[1:] {
+		i := 0
+		for i < len(output) && i < len(p) && output[i] == p[i] {
+			i++
+		}
+		output = output[:i]
+	}
+
+	return output
+}
+
+// replaceSubtree copies the tree rooted at n, with the subtree at the path replaced by the given node, or removed
+// if it is nil. The tree rooted at n is not modified.
+func replaceSubtree(n lib.Node, path []int, replacement *lib.Node) lib.Node {
+	output := n
+	output.Children = nil
+
+	for i, c := range n.Children {
+		switch {
+		case i != path[0]:
+			output.Children = append(output.Children, c)
+		case len(path) > 1:
+			output.Children = append(output.Children, replaceSubtree(c, path[1:], replacement))
+		case replacement != nil:
+			output.Children = append(output.Children, *replacement)
+		}
+	}
+
+	return output
+}
+
+// sameEdge checks if two edges have the same vertices
+func sameEdge(a, b lib.Edge) bool {
+	return reflect.DeepEqual(lib.RemoveDuplicates(append([]int{}, a.Vertices...)),
+		lib.RemoveDuplicates(append([]int{}, b.Vertices...)))
+}
+
+// validHD checks that the tree rooted at n is a hypertree decomposition of g, without printing the reason if not
+func validHD(n lib.Node, g lib.Graph) bool {
+	nodes := flattenTree(n)
+
+	edges := make(map[int]lib.Edge)
+	for _, e := range g.Edges.Slice() {
+		edges[e.Name] = e
+	}
+
+	occurrences := make(map[int]int) // number of bags holding a vertex, minus the tree edges between them
+	for _, rn := range nodes {
+		for _, e := range rn.node.Cover.Slice() {
+			if current, ok := edges[e.Name]; !ok || !sameEdge(current, e) {
+				return false
+			}
+		}
+		if !lib.Subset(rn.node.Bag, rn.node.Cover.Vertices()) {
+			return false
+		}
+
+		for _, v := range rn.node.Bag {
+			occurrences[v]++
+			if rn.parent >= 0 && lib.Subset([]int{v}, nodes[rn.parent].node.Bag) {
+				occurrences[v]--
+			}
+		}
+	}
+	for _, count := range occurrences {
+		if count != 1 {
+			return false // the bags holding the vertex are not connected
+		}
+	}
+
+	for _, e := range g.Edges.Slice() {
+		covered := false
+		for i := 0; i < len(nodes) && !covered; i++ {
+			covered = lib.Subset(e.Vertices, nodes[i].node.Bag)
+		}
+		if !covered {
+			return false
+		}
+	}
+
+	return noSCViolation(n)
+}
+
+// addChild copies the tree rooted at n, with the child added to the node at the path. The tree rooted at n is not
+// modified.
+func addChild(n lib.Node, path []int, child lib.Node) lib.Node {
+	output := n
+	output.Children = append([]lib.Node{}, n.Children...)
+
+	if len(path) == 0 {
+		output.Children = append(output.Children, child)
+	} else {
+		output.Children[path[0]] = addChild(n.Children[path[0]], path[1:], child)
+	}
+
+	return output
+}
+
+// attachLeaves adds a leaf for each of the edges below a node whose bag holds all vertices the edge shares with the
+// tree, failing if there is no such node for some edge
+func attachLeaves(root lib.Node, nodes []repairNode, edges []lib.Edge) (lib.Node, bool) {
+	var treeVertices []int
+	for _, rn := range nodes {
+		treeVertices = append(treeVertices, rn.node.Bag...)
+	}
+
+	for _, e := range edges {
+		shared := lib.Inter(e.Vertices, treeVertices)
+
+		found := false
+		for i := 0; i < len(nodes) && !found; i++ {
+			if len(shared) > 0 && lib.Subset(shared, nodes[i].node.Bag) {
+				leaf := lib.Node{Bag: lib.RemoveDuplicates(append([]int{}, e.Vertices...)), Cover: lib.NewEdges([]lib.Edge{e})}
+				root = addChild(root, nodes[i].path, leaf)
+				found = true
+			}
+		}
+		if !found {
+			return lib.Node{}, false
+		}
+	}
+
+	return root, true
+}
+
+// Repair updates a decomposition of an earlier version of the hypergraph, so that it becomes a decomposition of
+// width at most K of d.Graph, in which edges may have been added or removed. Edges are matched by their names.
+// New edges sharing only vertices of a single bag are put into new leaves below it. Otherwise, the nodes whose
+// covers use edges no longer present, and the nodes needed to cover the new edges, are replaced by
+// a decomposition of the edges not covered by the rest of the tree, searched for with the findDecomp of
+// DetKDecomp below their parent. If this fails, the subtree rooted at the parent is tried next, up to the full
+// tree, in which case the decomposition is searched for from scratch. The old decomposition is not modified.
+func (d *DetKDecomp) Repair(old lib.Decomp) (lib.Decomp, RepairReport) {
+	var report RepairReport
+	g := d.Graph
+
+	nodes := flattenTree(old.Root)
+	if reflect.DeepEqual(old, lib.Decomp{}) {
+		nodes = nil
+	}
+
+	edges := make(map[int]lib.Edge)
+	for _, e := range g.Edges.Slice() {
+		edges[e.Name] = e
+	}
+
+	// find the nodes affected by the changes
+	var affected [][]int
+	for _, rn := range nodes {
+		for _, e := range rn.node.Cover.Slice() {
+			if current, ok := edges[e.Name]; !ok || !sameEdge(current, e) {
+				affected = append(affected, rn.path)
+				break
+			}
+		}
+	}
+	validCovers := len(affected) == 0
+	var newEdges []lib.Edge
+	for _, e := range g.Edges.Slice() {
+		covered := false
+		var touching [][]int
+		for _, rn := range nodes {
+			covered = covered || lib.Subset(e.Vertices, rn.node.Bag)
+			if len(lib.Inter(e.Vertices, rn.node.Bag)) > 0 {
+				touching = append(touching, rn.path)
+			}
+		}
+		if !covered {
+			newEdges = append(newEdges, e)
+		}
+		switch {
+		case covered:
+		case len(touching) > 0:
+			affected = append(affected, commonPrefix(touching))
+		default:
+			affected = append(affected, []int{}) // the edge shares no vertex with the tree
+		}
+	}
+
+	if len(nodes) > 0 && len(affected) == 0 && validHD(old.Root, g) && old.CheckWidth() <= d.K {
+		report.Nodes, report.Reused = len(nodes), len(nodes)
+		return lib.Decomp{Graph: g, Root: old.Root}, report
+	}
+
+	// new edges sharing only vertices of a single bag are covered by a new leaf below it
+	if len(nodes) > 0 && validCovers && len(newEdges) > 0 {
+		if root, ok := attachLeaves(old.Root, nodes, newEdges); ok && validHD(root, g) && d.K >= 1 {
+			report.Nodes = len(nodes) + len(newEdges)
+			report.Reused = len(nodes)
+			report.Attempts = 1
+			return lib.Decomp{Graph: g, Root: root}, report
+		}
+	}
+
+	d.cache.Init()
+	d.counter.Init()
+	if d.pool == nil {
+		d.pool = &workerPool{}
+	}
+	if len(nodes) > 0 && len(affected) > 0 {
+		// indices of the nodes by their path
+		index := make(map[string]int)
+		for i, rn := range nodes {
+			index[fmt.Sprint(rn.path)] = i
+		}
+
+		for root := commonPrefix(affected); len(root) > 0 && !d.stopped(d.Stop); root = root[:len(root)-1] {
+			parent := nodes[nodes[index[fmt.Sprint(root)]].parent]
+
+			// the rest of the tree, and the edges it doesn't cover
+			var rest []repairNode
+			var restVertices []int
+			for _, rn := range nodes {
+				if !inSubtree(rn.path, root) {
+					rest = append(rest, rn)
+					restVertices = append(restVertices, rn.node.Bag...)
+				}
+			}
+			var uncovered []lib.Edge
+			for _, e := range g.Edges.Slice() {
+				covered := false
+				for i := 0; i < len(rest) && !covered; i++ {
+					covered = lib.Subset(e.Vertices, rest[i].node.Bag)
+				}
+				if !covered {
+					uncovered = append(uncovered, e)
+				}
+			}
+			H := lib.Graph{Edges: lib.NewEdges(uncovered)}
+
+			// vertices shared with the rest of the tree need to pass through the parent
+			if !lib.Subset(lib.Inter(H.Vertices(), restVertices), parent.node.Bag) {
+				continue
+			}
+			report.Attempts++
+
+			var replacement *lib.Node
+			if H.Edges.Len() > 0 {
+				decomp := d.findDecomp(H, parent.node.Bag, 0, d.Stop)
+				if reflect.DeepEqual(decomp, lib.Decomp{}) {
+					continue
+				}
+				replacement = &decomp.Root
+			}
+
+			repaired := lib.Decomp{Graph: g, Root: replaceSubtree(old.Root, root, replacement)}
+			if d.SubEdge {
+				repaired.RestoreSubedges()
+			}
+			if !validHD(repaired.Root, g) || repaired.CheckWidth() > d.K {
+				continue
+			}
+
+			report.Nodes = len(flattenTree(repaired.Root))
+			report.Reused = len(rest)
+			report.Replaced = len(nodes) - len(rest)
+			return repaired, report
+		}
+	}
+
+	// the repair failed, search for the decomposition from scratch
+	report.FullSearch = true
+	report.Replaced = len(nodes)
+	decomp := d.FindDecomp()
+	if !reflect.DeepEqual(decomp, lib.Decomp{}) {
+		decomp.Graph = g
+		report.Nodes = len(flattenTree(decomp.Root))
+	}
+
+	return decomp, report
+}
+
+// AlignEncoding renumbers a graph g parsed with the encoding e, so that the vertices and edges already named in
+// the encoding old keep the integers used there, as needed to repair a decomposition of a graph parsed earlier.
+// New names get integers not used by old. The encoding of the renumbered graph is returned with it.
+func AlignEncoding(g lib.Graph, e Encoding, old Encoding) (lib.Graph, Encoding) {
+	next := 1
+	for i := range old.names {
+		if i >= next {
+			next = i + 1
+		}
+	}
+
+	ids := make(map[string]int)
+	for name, i := range old.ids {
+		ids[name] = i
+	}
+	renumber := func(i int) int {
+		name := e.Name(i)
+		if _, ok := ids[name]; !ok {
+			ids[name] = next
+			next++
+		}
+		return ids[name]
+	}
+
+	var edges []lib.Edge
+	for _, edge := range g.Edges.Slice() {
+		renumbered := lib.Edge{Name: renumber(edge.Name)}
+		for _, v := range edge.Vertices {
+			renumbered.Vertices = append(renumbered.Vertices, renumber(v))
+		}
+		edges = append(edges, renumbered)
+	}
+
+	return lib.Graph{Edges: lib.NewEdges(edges)}, NewEncoding(ids)
+}
@@ -0,0 +1,56 @@
+package lib
+
+// searchstats.go counts the work of the algorithms besides their use of the cache, to compare changes to them
+
+import "sync/atomic"
+
+// SearchStats reports the work of an algorithm, over all searches since it was created
+type SearchStats struct {
+	Goroutines int64 // goroutines started by the algorithm itself, not counting those of the search generator
+	DetKCalls  int64 // subgraphs handed over to DetKDecomp by LogKDecomp or LogKHybrid
+}
+
+// searchCounter counts the work of an algorithm. Counters shared with the DetKDecomp instances an algorithm hands
+// subgraphs over to add up to the same stats.
+type searchCounter struct {
+	stats *SearchStats
+}
+
+// Init needs to be called before searching, for the work to be counted
+func (c *searchCounter) Init() {
+	if c.stats == nil {
+		c.stats = &SearchStats{}
+	}
+}
+
+// Share lets the other counter add up to the same stats
+func (c *searchCounter) Share(other *searchCounter) {
+	c.Init()
+	other.stats = c.stats
+}
+
+// Goroutine counts a goroutine started
+func (c *searchCounter) Goroutine() {
+	if c.stats != nil {
+		atomic.AddInt64(&c.stats.Goroutines, 1)
+	}
+}
+
+// DetKCall counts a subgraph handed over to DetKDecomp
+func (c *searchCounter) DetKCall() {
+	if c.stats != nil {
+		atomic.AddInt64(&c.stats.DetKCalls, 1)
+	}
+}
+
+// Stats returns the counters
+func (c *searchCounter) Stats() SearchStats {
+	if c.stats == nil {
+		return SearchStats{} // never used
+	}
+
+	return SearchStats{
+		Goroutines: atomic.LoadInt64(&c.stats.Goroutines),
+		DetKCalls:  atomic.LoadInt64(&c.stats.DetKCalls),
+	}
+}
@@ -0,0 +1,256 @@
+package lib
+
+// sql.go exports a hypertree decomposition as an SQL query, which evaluates the query the decomposition was
+// computed for via the semi-join passes of Yannakakis' algorithm, expressed as common table expressions (CTEs)
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+)
+
+// A SQLDialect determines the syntax used when exporting SQL
+type SQLDialect int
+
+// The supported SQL dialects
+const (
+	PostgreSQL SQLDialect = iota
+	SQLite
+)
+
+// GetSQLDialect returns the dialect of the given name
+func GetSQLDialect(name string) (SQLDialect, error) {
+	switch strings.ToLower(name) {
+	case "postgres", "postgresql":
+		return PostgreSQL, nil
+	case "sqlite", "sqlite3":
+		return SQLite, nil
+	}
+
+	return 0, fmt.Errorf("unknown SQL dialect %v", name)
+}
+
+// cte starts the definition of a CTE. PostgreSQL would otherwise inline CTEs used only once, and so lose the
+// intended evaluation order.
+func (s SQLDialect) cte(name string) string {
+	if s == PostgreSQL {
+		return quote(name) + " AS MATERIALIZED ("
+	}
+	return quote(name) + " AS ("
+}
+
+// A SQLTable names the table of a relation, together with its columns in the order of the vertices of the edge
+type SQLTable struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+}
+
+// A SQLMapping assigns to each edge name the table of its relation. Edges without a mapping use a table named
+// after the edge, with columns named after its vertices.
+type SQLMapping map[string]SQLTable
+
+// ReadSQLMapping reads a mapping in JSON format, as an object from edge names to tables, e.g.
+// {"R1": {"table": "orders", "columns": ["id", "customer"]}}
+func ReadSQLMapping(r io.Reader) (SQLMapping, error) {
+	var output SQLMapping
+
+	err := json.NewDecoder(r).Decode(&output)
+	if err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+func quote(identifier string) string {
+	return "\"" + strings.ReplaceAll(identifier, "\"", "\"\"") + "\""
+}
+
+// table determines the table and columns of an edge
+func (m SQLMapping) table(e lib.Edge, encoding Encoding) (SQLTable, error) {
+	name := encoding.Name(e.Name)
+
+	table, ok := m[name]
+	if !ok {
+		table.Table = name
+		for _, v := range e.Vertices {
+			table.Columns = append(table.Columns, encoding.Name(v))
+		}
+	}
+
+	if len(table.Columns) != len(e.Vertices) {
+		return SQLTable{}, fmt.Errorf("mapping of edge %v has %d columns, but the edge has %d vertices", name,
+			len(table.Columns), len(e.Vertices))
+	}
+
+	return table, nil
+}
+
+// equalities produces the condition that two CTEs agree on the given vertices
+func equalities(a, b string, vertices []int, encoding Encoding) string {
+	if len(vertices) == 0 {
+		return "1 = 1"
+	}
+
+	var conditions []string
+	for _, v := range vertices {
+		name := quote(encoding.Name(v))
+		conditions = append(conditions, quote(a)+"."+name+" = "+quote(b)+"."+name)
+	}
+
+	return strings.Join(conditions, " AND ")
+}
+
+// ToSQL exports the decomposition as an SQL query over the relations of the edges of g. Each node becomes a CTE
+// joining the relations of its cover (and of any other edges it has to check) projected to the bag, followed by
+// the bottom-up and top-down semi-join passes, and a final join producing the answers projected to the free
+// vertices. If free is empty, all vertices are returned. In boolean mode, the query only checks for the existence
+// of some answer.
+func ToSQL(d lib.Decomp, g lib.Graph, encoding Encoding, mapping SQLMapping, dialect SQLDialect, free []int,
+	boolean bool) (string, error) {
+	if reflect.DeepEqual(d, lib.Decomp{}) {
+		return "", ErrEmptyDecomp
+	}
+
+	t := newJoinTree(d)
+	assignment, err := AssignEdges(d, g)
+	if err != nil {
+		return "", err
+	}
+	original := make(map[int]lib.Edge) // edges in covers might have been changed by preprocessing
+	assigned := make([][]int, len(t.nodes))
+	for _, e := range g.Edges.Slice() {
+		original[e.Name] = e
+		assigned[assignment[e.Name]] = append(assigned[assignment[e.Name]], e.Name)
+	}
+	if len(free) == 0 {
+		free = g.Vertices()
+	}
+
+	var ctes []string
+
+	// 1. the relation of each node
+	for i := range t.nodes {
+		var names []int
+		for _, e := range t.nodes[i].Cover.Slice() {
+			names = append(names, e.Name)
+		}
+		names = append(names, assigned[i]...)
+
+		var from, conditions []string
+		columns := make(map[int]string) // first column found for each vertex
+
+		for j, name := range lib.RemoveDuplicates(names) {
+			e, ok := original[name]
+			if !ok {
+				return "", fmt.Errorf("cover of node %d uses edge %v, which is not part of the graph", i,
+					encoding.Name(name))
+			}
+			table, err := mapping.table(e, encoding)
+			if err != nil {
+				return "", err
+			}
+
+			alias := fmt.Sprintf("t%d", j)
+			from = append(from, quote(table.Table)+" "+alias)
+
+			for k, v := range e.Vertices {
+				column := alias + "." + quote(table.Columns[k])
+				if first, ok := columns[v]; ok {
+					conditions = append(conditions, first+" = "+column)
+				} else {
+					columns[v] = column
+				}
+			}
+		}
+
+		var selection []string
+		for _, v := range t.nodes[i].Bag {
+			selection = append(selection, columns[v]+" AS "+quote(encoding.Name(v)))
+		}
+		if len(selection) == 0 {
+			selection = append(selection, "1 AS "+quote("_"))
+		}
+
+		cte := dialect.cte(fmt.Sprintf("n%d", i)) + "SELECT DISTINCT " + strings.Join(selection, ", ") +
+			" FROM " + strings.Join(from, ", ")
+		if len(conditions) > 0 {
+			cte = cte + " WHERE " + strings.Join(conditions, " AND ")
+		}
+		ctes = append(ctes, fmt.Sprintf("-- node %d: cover %v, bag %v\n", i, encoding.Edges(t.nodes[i].Cover),
+			encoding.Vertices(t.nodes[i].Bag))+cte+")")
+	}
+
+	// 2. bottom-up semi-join pass
+	for i := len(t.nodes) - 1; i >= 0; i-- {
+		node := fmt.Sprintf("n%d", i)
+		var conditions []string
+		for _, c := range t.children[i] {
+			child := fmt.Sprintf("u%d", c)
+			shared := lib.Inter(t.nodes[i].Bag, t.nodes[c].Bag)
+			conditions = append(conditions, "EXISTS (SELECT 1 FROM "+quote(child)+" WHERE "+
+				equalities(child, node, shared, encoding)+")")
+		}
+
+		cte := dialect.cte(fmt.Sprintf("u%d", i)) + "SELECT * FROM " + quote(node)
+		if len(conditions) > 0 {
+			cte = cte + " WHERE " + strings.Join(conditions, " AND ")
+		}
+		ctes = append(ctes, cte+")")
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("WITH\n")
+
+	if boolean {
+		buffer.WriteString(strings.Join(ctes, ",\n"))
+		buffer.WriteString("\nSELECT EXISTS (SELECT 1 FROM " + quote("u0") + ");\n")
+		return buffer.String(), nil
+	}
+
+	// 3. top-down semi-join pass
+	ctes = append(ctes, dialect.cte("d0")+"SELECT * FROM "+quote("u0")+")")
+	for i := 1; i < len(t.nodes); i++ {
+		node := fmt.Sprintf("u%d", i)
+		parent := fmt.Sprintf("d%d", t.parent[i])
+		shared := lib.Inter(t.nodes[i].Bag, t.nodes[t.parent[i]].Bag)
+
+		ctes = append(ctes, dialect.cte(fmt.Sprintf("d%d", i))+"SELECT * FROM "+quote(node)+
+			" WHERE EXISTS (SELECT 1 FROM "+quote(parent)+" WHERE "+equalities(parent, node, shared, encoding)+"))")
+	}
+
+	buffer.WriteString(strings.Join(ctes, ",\n"))
+
+	// 4. final join along the tree
+	source := make(map[int]string)
+	for i := range t.nodes {
+		for _, v := range t.nodes[i].Bag {
+			if _, ok := source[v]; !ok {
+				source[v] = fmt.Sprintf("d%d", i)
+			}
+		}
+	}
+
+	var selection []string
+	for _, v := range free {
+		name := quote(encoding.Name(v))
+		selection = append(selection, quote(source[v])+"."+name+" AS "+name)
+	}
+
+	buffer.WriteString("\nSELECT DISTINCT " + strings.Join(selection, ", ") + "\nFROM " + quote("d0"))
+	for i := 1; i < len(t.nodes); i++ {
+		node := fmt.Sprintf("d%d", i)
+		parent := fmt.Sprintf("d%d", t.parent[i])
+		shared := lib.Inter(t.nodes[i].Bag, t.nodes[t.parent[i]].Bag)
+
+		buffer.WriteString("\n  JOIN " + quote(node) + " ON " + equalities(parent, node, shared, encoding))
+	}
+	buffer.WriteString(";\n")
+
+	return buffer.String(), nil
+}
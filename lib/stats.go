@@ -0,0 +1,305 @@
+package lib
+
+// stats.go computes structural properties of hypergraphs, which help in choosing the settings for decomposing them
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+)
+
+// Stats collects the structural properties of a hypergraph
+type Stats struct {
+	Name              string      `json:"name"`
+	Vertices          int         `json:"vertices"`
+	Edges             int         `json:"edges"`
+	ArityMin          int         `json:"arityMin"`
+	ArityMax          int         `json:"arityMax"`
+	ArityAvg          float64     `json:"arityAvg"`
+	Arities           map[int]int `json:"arities"` // number of edges of each arity
+	DegreeMax         int         `json:"degreeMax"`
+	DegreeAvg         float64     `json:"degreeAvg"`
+	BIP               int         `json:"bip"`   // largest intersection of two edges
+	BMIP3             int         `json:"bmip3"` // largest intersection of three edges
+	VCDimension       int         `json:"vcDimension"`
+	VCExact           bool        `json:"vcExact"` // false if the search for the VC dimension was cut short
+	Components        int         `json:"components"`
+	AlphaAcyclic      bool        `json:"alphaAcyclic"`
+	LargestHinge      int         `json:"largestHinge"`      // number of edges in the largest hinge
+	TypeCollapseSaved int         `json:"typeCollapseSaved"` // vertices removed by the type collapse
+	HWLower           int         `json:"hwLower"`
+	HWUpper           int         `json:"hwUpper"`
+	GHWUpper          int         `json:"ghwUpper"`
+}
+
+// VCLimit bounds the number of vertex sets checked when determining the VC dimension
+var VCLimit = 100000
+
+// bmip3 computes the largest intersection of any three edges, skipping pairs which can't improve on the best
+func bmip3(edges []lib.Edge) int {
+	output := 0
+
+	for i := range edges {
+		for j := i + 1; j < len(edges); j++ {
+			pair := lib.Inter(edges[i].Vertices, edges[j].Vertices)
+			if len(pair) <= output {
+				continue
+			}
+			for k := j + 1; k < len(edges); k++ {
+				if tmp := len(lib.Inter(pair, edges[k].Vertices)); tmp > output {
+					output = tmp
+				}
+			}
+		}
+	}
+
+	return output
+}
+
+// shattered checks if the traces of the edges on the set of vertices include every subset of it
+func shattered(set []int, edges []lib.Edge) bool {
+	traces := make(map[string]bool)
+
+	for _, e := range edges {
+		var sb strings.Builder
+		for _, v := range set {
+			if len(lib.Inter(e.Vertices, []int{v})) > 0 {
+				sb.WriteByte('1')
+			} else {
+				sb.WriteByte('0')
+			}
+		}
+		traces[sb.String()] = true
+	}
+
+	return len(traces) == 1<<len(set)
+}
+
+// VCDimension computes the VC dimension of the hypergraph, by extending shattered sets one vertex at a time. As
+// this is exponential in general, at most VCLimit sets are checked, and the result is only a lower bound if the
+// search was cut short.
+func VCDimension(g lib.Graph) (int, bool) {
+	edges := g.Edges.Slice()
+	vertices := append([]int{}, g.Vertices()...)
+	sort.Ints(vertices)
+
+	current := [][]int{{}} // the shattered sets of the current size
+	output := 0
+	checked := 0
+
+	for len(current) > 0 {
+		var next [][]int
+
+		for _, set := range current {
+			for _, v := range vertices {
+				if len(set) > 0 && v <= set[len(set)-1] {
+					continue // consider each set only once
+				}
+				if checked >= VCLimit {
+					return output, false
+				}
+				checked++
+
+				candidate := append(append([]int{}, set...), v)
+				if shattered(candidate, edges) {
+					next = append(next, candidate)
+				}
+			}
+		}
+
+		if len(next) > 0 {
+			output = len(next[0])
+		}
+		current = next
+	}
+
+	return output, true
+}
+
+// degeneracy computes the largest minimum degree of any subgraph of the primal graph, a lower bound on its
+// treewidth
+func degeneracy(g lib.Graph) int {
+	neighbours := make(map[int]map[int]bool)
+	for _, v := range g.Vertices() {
+		neighbours[v] = make(map[int]bool)
+	}
+	for _, e := range g.Edges.Slice() {
+		for _, u := range e.Vertices {
+			for _, v := range e.Vertices {
+				if u != v {
+					neighbours[u][v] = true
+				}
+			}
+		}
+	}
+
+	output := 0
+	for len(neighbours) > 0 {
+		min, minV := -1, 0
+		for v, n := range neighbours {
+			if min < 0 || len(n) < min || (len(n) == min && v < minV) {
+				min, minV = len(n), v
+			}
+		}
+		if min > output {
+			output = min
+		}
+
+		for u := range neighbours[minV] {
+			delete(neighbours[u], minV)
+		}
+		delete(neighbours, minV)
+	}
+
+	return output
+}
+
+// greedyCover covers the vertices of a bag with edges, picking the edge covering the most uncovered vertices
+func greedyCover(bag []int, edges []lib.Edge) int {
+	uncovered := bag
+	output := 0
+
+	for len(uncovered) > 0 {
+		best := -1
+		bestCovered := 0
+		for i, e := range edges {
+			if covered := len(lib.Inter(uncovered, e.Vertices)); covered > bestCovered {
+				best, bestCovered = i, covered
+			}
+		}
+		if best < 0 {
+			break // vertices of the bag not in any edge, can't happen for decompositions of g
+		}
+
+		uncovered = lib.Diff(uncovered, edges[best].Vertices)
+		output++
+	}
+
+	return output
+}
+
+// GetStats computes the structural properties of the hypergraph
+func GetStats(g lib.Graph, name string) Stats {
+	edges := g.Edges.Slice()
+	vertices := g.Vertices()
+
+	output := Stats{Name: name, Vertices: len(vertices), Edges: len(edges), Arities: make(map[int]int)}
+	if len(edges) == 0 {
+		return output
+	}
+
+	// arity and degree
+	output.ArityMin = math.MaxInt32
+	degree := make(map[int]int)
+	sum := 0
+	for _, e := range edges {
+		arity := len(e.Vertices)
+		output.Arities[arity]++
+		sum = sum + arity
+		if arity < output.ArityMin {
+			output.ArityMin = arity
+		}
+		if arity > output.ArityMax {
+			output.ArityMax = arity
+		}
+		for _, v := range lib.RemoveDuplicates(append([]int{}, e.Vertices...)) {
+			degree[v]++
+		}
+	}
+	output.ArityAvg = float64(sum) / float64(len(edges))
+
+	sum = 0
+	for _, d := range degree {
+		sum = sum + d
+		if d > output.DegreeMax {
+			output.DegreeMax = d
+		}
+	}
+	output.DegreeAvg = float64(sum) / float64(len(vertices))
+
+	// intersections and VC dimension
+	output.BIP = g.GetBIP()
+	output.BMIP3 = bmip3(edges)
+	output.VCDimension, output.VCExact = VCDimension(g)
+	output.Components = len(ConnectedComponents(g))
+
+	// preprocessing
+	reduced, _ := g.GYÖReduct()
+	output.AlphaAcyclic = reduced.Edges.Len() == 0
+	output.LargestHinge = lib.GetHingeTree(g).GetLargestGraph().Edges.Len()
+	_, _, output.TypeCollapseSaved = g.TypeCollapse()
+
+	// bounds on the hypertree width
+	if output.AlphaAcyclic {
+		output.HWLower, output.HWUpper, output.GHWUpper = 1, 1, 1
+		return output
+	}
+
+	// each bag of an HD of width k has at most k * ArityMax vertices, so its treewidth is below that
+	output.HWLower = int(math.Ceil(float64(degeneracy(g)+1) / float64(output.ArityMax)))
+	if output.HWLower < 2 {
+		output.HWLower = 2 // not acyclic
+	}
+
+	// covering the bags of a tree decomposition gives a GHD, and hw <= 3 ghw + 1 (Adler, Gottlob, Grohe 2007)
+	td := MinFill(g)
+	var visit func(n lib.Node)
+	visit = func(n lib.Node) {
+		if width := greedyCover(n.Bag, edges); width > output.GHWUpper {
+			output.GHWUpper = width
+		}
+		for _, c := range n.Children {
+			visit(c)
+		}
+	}
+	visit(td.Root)
+
+	output.HWUpper = 3*output.GHWUpper + 1
+	if output.HWUpper > len(edges) {
+		output.HWUpper = len(edges)
+	}
+	if output.GHWUpper > output.HWUpper {
+		output.GHWUpper = output.HWUpper
+	}
+
+	return output
+}
+
+func (s Stats) String() string {
+	var arities []int
+	for a := range s.Arities {
+		arities = append(arities, a)
+	}
+	sort.Ints(arities)
+
+	var distribution []string
+	for _, a := range arities {
+		distribution = append(distribution, fmt.Sprintf("%d: %d", a, s.Arities[a]))
+	}
+
+	vc := fmt.Sprint(s.VCDimension)
+	if !s.VCExact {
+		vc = ">= " + vc
+	}
+
+	var sb strings.Builder
+	fmt.Fprintln(&sb, s.Name)
+	fmt.Fprintln(&sb, "  Vertices:", s.Vertices)
+	fmt.Fprintln(&sb, "  Edges:", s.Edges)
+	fmt.Fprintf(&sb, "  Arity: min %d, max %d, avg %.2f\n", s.ArityMin, s.ArityMax, s.ArityAvg)
+	fmt.Fprintln(&sb, "  Arity distribution:", strings.Join(distribution, ", "))
+	fmt.Fprintf(&sb, "  Degree: max %d, avg %.2f\n", s.DegreeMax, s.DegreeAvg)
+	fmt.Fprintln(&sb, "  BIP:", s.BIP)
+	fmt.Fprintln(&sb, "  3-BMIP:", s.BMIP3)
+	fmt.Fprintln(&sb, "  VC dimension:", vc)
+	fmt.Fprintln(&sb, "  Connected components:", s.Components)
+	fmt.Fprintln(&sb, "  Alpha-acyclic:", s.AlphaAcyclic)
+	fmt.Fprintln(&sb, "  Largest hinge:", s.LargestHinge, "edges")
+	fmt.Fprintln(&sb, "  Type collapse removes:", s.TypeCollapseSaved, "vertices")
+	fmt.Fprintf(&sb, "  Hypertree width: %d <= hw <= %d (ghw <= %d)\n", s.HWLower, s.HWUpper, s.GHWUpper)
+
+	return sb.String()
+}
@@ -0,0 +1,28 @@
+package lib
+
+// stop.go allows running searches to be cancelled
+
+import "sync/atomic"
+
+// A StopFlag tells searches to give up, e.g. once another search has already succeeded. A nil StopFlag is never
+// stopped.
+type StopFlag struct {
+	stopped int32
+	parent  *StopFlag
+}
+
+// Child returns a new flag, which is stopped once either it or the flag it was derived from is stopped. This allows
+// cancelling part of a search without affecting the rest.
+func (s *StopFlag) Child() *StopFlag {
+	return &StopFlag{parent: s}
+}
+
+// Stop signals all searches using the flag to give up
+func (s *StopFlag) Stop() {
+	atomic.StoreInt32(&s.stopped, 1)
+}
+
+// Stopped checks if the searches using the flag should give up
+func (s *StopFlag) Stopped() bool {
+	return s != nil && (atomic.LoadInt32(&s.stopped) == 1 || s.parent.Stopped())
+}
@@ -0,0 +1,362 @@
+package lib
+
+// treewidth.go supports tree decompositions of plain graphs, in the formats of the PACE 2017 challenge (see
+// pacechallenge.org/2017/treewidth/), together with a heuristic based on elimination orderings
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+)
+
+// GetGraphGR parses a graph in the .gr format of PACE 2017. Vertex i is named "V<i>", each edge gets the name
+// "E<j>" after its position j in the input, and each isolated vertex is put into a unary edge "I<i>", so that
+// it is part of any decomposition.
+func GetGraphGR(s string) (lib.Graph, Encoding, error) {
+	var buffer bytes.Buffer
+	headerFound := false
+	numVertices := 0
+	var edges []string
+	connected := make(map[int]bool)
+
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(s)+1)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) == 0 || fields[0] == "c" {
+			continue // skip empty lines and comments
+		}
+		if fields[0] == "p" {
+			if len(fields) != 4 || fields[1] != "tw" || headerFound {
+				return lib.Graph{}, Encoding{}, fmt.Errorf("line %d: malformed header", line)
+			}
+			n, err := strconv.Atoi(fields[2])
+			if err != nil || n < 0 {
+				return lib.Graph{}, Encoding{}, fmt.Errorf("line %d: malformed header", line)
+			}
+			numVertices = n
+			headerFound = true
+			continue
+		}
+		if !headerFound {
+			return lib.Graph{}, Encoding{}, fmt.Errorf("line %d: edge before header", line)
+		}
+		if len(fields) != 2 {
+			return lib.Graph{}, Encoding{}, fmt.Errorf("line %d: edge must have two endpoints", line)
+		}
+
+		for _, f := range fields {
+			v, err := strconv.Atoi(f)
+			if err != nil || v < 1 || v > numVertices {
+				return lib.Graph{}, Encoding{}, fmt.Errorf("line %d: %v is not a vertex", line, f)
+			}
+			connected[v] = true
+		}
+		edges = append(edges, fmt.Sprintf("E%d(V%s,V%s)", len(edges)+1, fields[0], fields[1]))
+	}
+
+	if !headerFound {
+		return lib.Graph{}, Encoding{}, fmt.Errorf("missing header")
+	}
+	for v := 1; v <= numVertices; v++ {
+		if !connected[v] {
+			edges = append(edges, fmt.Sprintf("I%d(V%d)", v, v))
+		}
+	}
+	if len(edges) == 0 {
+		return lib.Graph{}, Encoding{}, fmt.Errorf("graph has no vertices")
+	}
+
+	buffer.WriteString(strings.Join(edges, ",\n"))
+	buffer.WriteString(".")
+
+	return GetGraph(buffer.String())
+}
+
+// VertexEdges produces a unary edge for each of the given vertices, named like the vertex. Used as covers, these
+// turn the width of a hypertree decomposition into the size of its largest bag.
+func VertexEdges(vertices []int) lib.Edges {
+	var output []lib.Edge
+
+	for _, v := range vertices {
+		output = append(output, lib.Edge{Name: v, Vertices: []int{v}})
+	}
+
+	return lib.NewEdges(output)
+}
+
+// TreeWidth returns the width of a tree decomposition, i.e. the size of its largest bag minus one
+func TreeWidth(d lib.Decomp) int {
+	var visit func(n lib.Node) int
+	visit = func(n lib.Node) int {
+		output := len(n.Bag)
+		for _, c := range n.Children {
+			if size := visit(c); size > output {
+				output = size
+			}
+		}
+		return output
+	}
+
+	return visit(d.Root) - 1
+}
+
+// MinFill computes a tree decomposition of the primal graph of g, using the elimination ordering which greedily
+// picks the vertex introducing the fewest new edges, with ties broken by the smaller degree
+func MinFill(g lib.Graph) lib.Decomp {
+	neighbours := make(map[int]map[int]bool)
+	for _, v := range g.Vertices() {
+		neighbours[v] = make(map[int]bool)
+	}
+	for _, e := range g.Edges.Slice() {
+		for _, u := range e.Vertices {
+			for _, v := range e.Vertices {
+				if u != v {
+					neighbours[u][v] = true
+				}
+			}
+		}
+	}
+
+	remaining := append([]int{}, g.Vertices()...) // the graph keeps its own slice of vertices
+	sort.Ints(remaining)                          // for a deterministic result
+	position := make(map[int]int)
+	var order []int
+	var bags [][]int
+
+	for len(remaining) > 0 {
+		best, bestFill := -1, 0
+		for i, v := range remaining {
+			fill := 0
+			for u := range neighbours[v] {
+				for w := range neighbours[v] {
+					if u < w && !neighbours[u][w] {
+						fill++
+					}
+				}
+			}
+			if best < 0 || fill < bestFill ||
+				(fill == bestFill && len(neighbours[v]) < len(neighbours[remaining[best]])) {
+				best, bestFill = i, fill
+			}
+		}
+
+		v := remaining[best]
+		remaining = append(remaining[:best], remaining[best+1:]...)
+
+		bag := []int{v}
+		for u := range neighbours[v] {
+			bag = append(bag, u)
+			delete(neighbours[u], v)
+			for w := range neighbours[v] {
+				if u != w {
+					neighbours[u][w] = true
+				}
+			}
+		}
+		sort.Ints(bag[1:])
+
+		position[v] = len(order)
+		order = append(order, v)
+		bags = append(bags, bag)
+	}
+
+	// the parent of each bag is the bag of its neighbour eliminated first, or the last bag if there is none
+	children := make([][]int, len(order))
+	for i := 0; i < len(order)-1; i++ {
+		parent := len(order) - 1
+		for _, u := range bags[i][1:] {
+			if position[u] < parent {
+				parent = position[u]
+			}
+		}
+		children[parent] = append(children[parent], i)
+	}
+
+	var build func(i int) lib.Node
+	build = func(i int) lib.Node {
+		output := lib.Node{Bag: bags[i], Cover: VertexEdges(bags[i])}
+		for _, c := range children[i] {
+			output.Children = append(output.Children, build(c))
+		}
+		return output
+	}
+
+	return lib.Decomp{Graph: g, Root: build(len(order) - 1)}
+}
+
+// vertexNumbers assigns to each vertex its number in the PACE formats. Vertices named "V<i>", as produced by
+// GetGraphGR, keep their number i if these are exactly the numbers 1 to n. Otherwise, all vertices are numbered in
+// order of appearance.
+func vertexNumbers(g lib.Graph, encoding Encoding) (map[int]int, bool) {
+	output := make(map[int]int)
+	used := make(map[int]bool)
+
+	original := true
+	for _, v := range g.Vertices() {
+		i, ok := nameNumber(encoding.Name(v), "V")
+		if !ok || i > len(g.Vertices()) || used[i] {
+			original = false
+			break
+		}
+		output[v] = i
+		used[i] = true
+	}
+	if original {
+		return output, true
+	}
+
+	for i, v := range g.Vertices() {
+		output[v] = i + 1
+	}
+	return output, false
+}
+
+// WriteTD writes a tree decomposition of g in the .td format of PACE 2017. If the vertices of g don't come from a
+// .gr file, they are renumbered, and comments list the original name of each number.
+func WriteTD(w io.Writer, d lib.Decomp, g lib.Graph, encoding Encoding) error {
+	numbers, original := vertexNumbers(g, encoding)
+
+	numVertices := 0
+	for _, i := range numbers {
+		if i > numVertices {
+			numVertices = i
+		}
+	}
+
+	t := newJoinTree(d)
+	writer := bufio.NewWriter(w)
+
+	if !original {
+		for _, v := range g.Vertices() {
+			fmt.Fprintf(writer, "c %d %s\n", numbers[v], encoding.Name(v))
+		}
+	}
+	fmt.Fprintf(writer, "s td %d %d %d\n", len(t.nodes), TreeWidth(d)+1, numVertices)
+
+	for i := range t.nodes {
+		var bag []int
+		for _, v := range t.nodes[i].Bag {
+			bag = append(bag, numbers[v])
+		}
+		sort.Ints(bag)
+
+		fmt.Fprintf(writer, "b %d", i+1)
+		for _, v := range bag {
+			fmt.Fprintf(writer, " %d", v)
+		}
+		fmt.Fprintln(writer)
+	}
+	for i := 1; i < len(t.nodes); i++ {
+		fmt.Fprintf(writer, "%d %d\n", t.parent[i]+1, i+1)
+	}
+
+	return writer.Flush()
+}
+
+// ReadTD reads a tree decomposition of g in the .td format of PACE 2017, using the numbering of WriteTD. The first
+// bag becomes the root.
+func ReadTD(r io.Reader, g lib.Graph, encoding Encoding) (lib.Decomp, error) {
+	numbers, _ := vertexNumbers(g, encoding)
+	vertices := make(map[int]int)
+	for v, i := range numbers {
+		vertices[i] = v
+	}
+
+	var bags [][]int
+	var tree [][2]int
+	headerFound := false
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] == "c" {
+			continue
+		}
+
+		switch {
+		case fields[0] == "s":
+			if len(fields) != 5 || fields[1] != "td" || headerFound {
+				return lib.Decomp{}, fmt.Errorf("line %d: malformed header", line)
+			}
+			n, err := strconv.Atoi(fields[2])
+			if err != nil || n < 1 {
+				return lib.Decomp{}, fmt.Errorf("line %d: malformed header", line)
+			}
+			bags = make([][]int, n)
+			headerFound = true
+		case !headerFound:
+			return lib.Decomp{}, fmt.Errorf("line %d: content before header", line)
+		case fields[0] == "b":
+			i, err := strconv.Atoi(fields[1])
+			if err != nil || i < 1 || i > len(bags) {
+				return lib.Decomp{}, fmt.Errorf("line %d: invalid bag %v", line, fields[1])
+			}
+			bags[i-1] = []int{}
+			for _, f := range fields[2:] {
+				j, err := strconv.Atoi(f)
+				v, ok := vertices[j]
+				if err != nil || !ok {
+					return lib.Decomp{}, fmt.Errorf("line %d: unknown vertex %v", line, f)
+				}
+				bags[i-1] = append(bags[i-1], v)
+			}
+		default:
+			if len(fields) != 2 {
+				return lib.Decomp{}, fmt.Errorf("line %d: malformed tree edge", line)
+			}
+			i, err1 := strconv.Atoi(fields[0])
+			j, err2 := strconv.Atoi(fields[1])
+			if err1 != nil || err2 != nil || i < 1 || j < 1 || i > len(bags) || j > len(bags) {
+				return lib.Decomp{}, fmt.Errorf("line %d: malformed tree edge", line)
+			}
+			tree = append(tree, [2]int{i - 1, j - 1})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return lib.Decomp{}, err
+	}
+	if !headerFound {
+		return lib.Decomp{}, fmt.Errorf("missing header")
+	}
+	if len(tree) != len(bags)-1 {
+		return lib.Decomp{}, fmt.Errorf("expected %d tree edges, found %d", len(bags)-1, len(tree))
+	}
+
+	neighbours := make([][]int, len(bags))
+	for _, e := range tree {
+		neighbours[e[0]] = append(neighbours[e[0]], e[1])
+		neighbours[e[1]] = append(neighbours[e[1]], e[0])
+	}
+
+	visited := make([]bool, len(bags))
+	var build func(i int) lib.Node
+	build = func(i int) lib.Node {
+		visited[i] = true
+		output := lib.Node{Bag: bags[i], Cover: VertexEdges(bags[i])}
+		for _, j := range neighbours[i] {
+			if !visited[j] {
+				output.Children = append(output.Children, build(j))
+			}
+		}
+		return output
+	}
+	root := build(0)
+
+	for i := range visited {
+		if !visited[i] {
+			return lib.Decomp{}, fmt.Errorf("tree edges don't form a tree")
+		}
+	}
+
+	return lib.Decomp{Graph: g, Root: root}, nil
+}
@@ -5,18 +5,22 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"math"
 	"os"
 	"reflect"
 	"runtime"
 	"runtime/pprof"
+	"strings"
 	"time"
 
 	algo "github.com/cem-okulmus/BalancedGo/algorithms"
 	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
 )
 
 // Decomp used to improve readability
@@ -51,15 +55,66 @@ type labelTime struct {
 	label string
 }
 
+// balanceOptions determines the balance from the flags. If neither a ratio nor a measure other than edges is
+// chosen, the balance is left unset, so that the balance factor is used.
+func balanceOptions(ratio string, measureName string, balFactor int) (logk.Balance, error) {
+	measure, err := logk.ParseMeasure(measureName)
+	if err != nil {
+		return logk.Balance{}, err
+	}
+	if ratio == "" && measure == logk.EdgeMeasure {
+		return logk.Balance{}, nil
+	}
+
+	balance := logk.NewBalance(balFactor)
+	if ratio != "" {
+		if balance, err = logk.ParseBalance(ratio); err != nil {
+			return balance, err
+		}
+	}
+	balance.Measure = measure
+
+	return balance, nil
+}
+
 func (l labelTime) String() string {
 	return fmt.Sprintf("%s : %.5f ms", l.label, l.time)
 }
 
-func outputStanza(algorithm string, decomp Decomp, times []labelTime, graph Graph, gml string, K int, skipCheck bool) {
+// runContext keeps track of everything known about the input of a run, which is needed to restore the
+// decomposition of the preprocessed graph and to print it using the original names
+type runContext struct {
+	encoding   logk.Encoding    // names of vertices and edges, as used in the input
+	original   Graph            // the graph as parsed, before any preprocessing
+	reductions []logk.Reduction // performed by the preprocessing steps, in order
+	db         logk.Database    // relations of the edges, if already loaded
+}
+
+// restore reverts the preprocessing steps on a decomposition of the reduced graph
+func (ctx runContext) restore(decomp Decomp, reduced Graph) Decomp {
+	if !reflect.DeepEqual(decomp, Decomp{}) || (len(ctx.reductions) > 0 && reduced.Edges.Len() == 0) {
+		for i := len(ctx.reductions) - 1; i >= 0; i-- {
+			var result bool
+			decomp.Root, result = ctx.reductions[i].Restore(decomp.Root)
+			if !result {
+				fmt.Println("Partial decomp:", ctx.encoding.Node(decomp.Root))
+				log.Panicln("Restoring preprocessing step failed")
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(decomp, Decomp{}) {
+		decomp.Graph = ctx.original
+	}
+
+	return decomp
+}
+
+func outputStanza(algorithm string, decomp Decomp, times []labelTime, ctx runContext, gml string, K int, skipCheck bool) {
 	decomp.RestoreSubedges()
 
 	fmt.Println("Used algorithm: " + algorithm)
-	fmt.Println("Result ( ran with K =", K, ")\n", decomp)
+	fmt.Println("Result ( ran with K =", K, ")\n", ctx.encoding.Decomp(decomp))
 
 	// Print the times
 	var sumTotal float64
@@ -77,7 +132,7 @@ func outputStanza(algorithm string, decomp Decomp, times []labelTime, graph Grap
 	fmt.Println("\nWidth: ", decomp.CheckWidth())
 	var correct bool
 	if !skipCheck {
-		correct = decomp.Correct(graph)
+		correct = decomp.Correct(ctx.original)
 	} else {
 		correct = true
 	}
@@ -88,18 +143,22 @@ func outputStanza(algorithm string, decomp Decomp, times []labelTime, graph Grap
 		check(err)
 
 		defer f.Close()
-		f.WriteString(decomp.ToGML())
+		f.WriteString(ctx.encoding.GML(decomp))
 		f.Sync()
 	}
 }
 
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// runDecompose implements the decompose command, with the arguments following it
+func runDecompose(args []string) int {
 
 	// ==============================================
 	// Command-Line Argument Parsing
 
-	flagSet := flag.NewFlagSet("", flag.ContinueOnError)
-	flagSet.SetOutput(ioutil.Discard)
+	flagSet := flag.NewFlagSet("decompose", flag.ContinueOnError)
 
 	// input flags
 	graphPath := flagSet.String("graph", "", "input (for format see hyperbench.dbai.tuwien.ac.at/downloads/manual.pdf)")
@@ -109,6 +168,9 @@ func main() {
 
 	// algorithms  flags
 	logK := flagSet.Bool("logk", false, "Use non-hybrid LogKDecomp algorithm (not recommended)")
+	detK := flagSet.Bool("detk", false, "Use the sequential DetKDecomp algorithm")
+	detKSubEdge := flagSet.Bool("detk-subedge", false, "Use the sequential DetKDecomp algorithm, with subedges under a local BIP")
+	detKParallel := flagSet.Bool("detk-parallel", false, "let DetKDecomp, on its own or within the hybrid algorithm, explore covers and components concurrently")
 	// logKHybrid := flagSet.Bool("logkHybrid", false, "Use DetK - LogK Hybrid algorithm. Choose non-zero values to use specific forms of hybridisation, otherwise the default one is chosen.")
 
 	// heuristic flags
@@ -117,69 +179,73 @@ func main() {
 	gyö := flagSet.Bool("g", false, "perform a GYÖ reduct")
 	typeC := flagSet.Bool("t", false, "perform a Type Collapse")
 	hingeFlag := flagSet.Bool("h", false, "use hingeTree Optimization")
+	pre := flagSet.String("pre", "", "comma-separated list of preprocessing steps, applied in order, out of\n\t"+
+		strings.Join(logk.PreprocessingSteps, ", ")+" (replaces -t, -g and -h)")
 
 	//other optional  flags
 	logKHybridCustom := flagSet.Int("logkHybridCustom", 0, "Use DetK - LogK Hybrid algorithm, but non-standard form of hybridisation.")
 	cpuprofile := flagSet.String("cpuprofile", "", "write cpu profile to file")
 	logging := flagSet.Bool("log", false, "turn on extensive logs")
-	balanceFactorFlag := flagSet.Int("balfactor", 2, "Changes the factor that balanced separator check uses, default 2")
+	balanceFactorFlag := flagSet.Int("balfactor", 2, "Changes the factor that balanced separator check uses")
+	balanceRatio := flagSet.String("balance", "", "largest share of a component allowed for balanced separators, as a ratio such as 3/5 (replaces -balfactor)")
+	balanceMeasure := flagSet.String("balmeasure", "edges", "measure of components for balanced separators: edges, vertices or weights (using -weights)")
 	numCPUs := flagSet.Int("cpu", -1, "Set number of CPUs to use")
+	timeout := flagSet.Duration("timeout", 0, "stop the search after the given time, e.g. 30s, exiting with code 3")
+	memLimit := flagSet.Int("mem", 0, "memory limit in MB, near which caches are emptied, fewer workers used and DetKDecomp preferred,\n\t"+
+		"and beyond which the search stops")
 	bench := flagSet.Bool("bench", false, "Benchmark mode, reduces unneeded output (incompatible with -log flag)")
 	gml := flagSet.String("gml", "", "Output the produced decomposition into the specified gml file ")
 	pace := flagSet.Bool("pace", false, "Use PACE 2019 format for graphs (see pacechallenge.org/2019/htd/htd_format/)")
 	meta := flagSet.Int("meta", 0, "meta parameter for LogKHybrid, to be used when choosing non-zero values in the logKHybrid flag.")
 
-	parseError := flagSet.Parse(os.Args[1:])
-	if parseError != nil {
-		fmt.Print("Parse Error:\n", parseError.Error(), "\n\n")
+	// query evaluation flags
+	dbPath := flagSet.String("db", "", "directory holding a CSV file <edge name>.csv for the relation of each edge")
+	eval := flagSet.Bool("eval", false, "evaluate the query over the relations in the db directory, using the produced HD")
+	boolean := flagSet.Bool("boolean", false, "only decide if the query over the db directory has any answer")
+	count := flagSet.Bool("count", false, "count the answers of the query over the db directory, without computing them")
+	free := flagSet.String("free", "", "comma-separated list of free vertices, to which answers are projected when using -eval, -count or -sql")
+	sqlPath := flagSet.String("sql", "", "export the produced HD as an SQL query into the specified file")
+	sqlDialect := flagSet.String("sqldialect", "postgres", "SQL dialect used by -sql, either postgres or sqlite")
+	sqlMapping := flagSet.String("sqlmap", "", "JSON file mapping edge names to tables and columns, used by -sql")
+
+	// normalisation flags
+	normalise := flagSet.Bool("normalise", false, "remove redundant nodes and shrink covers of the produced HD (same as -redundant -mincover)")
+	redundant := flagSet.Bool("redundant", false, "remove nodes of the produced HD whose bag is a subset of a neighbouring one")
+	minCover := flagSet.Bool("mincover", false, "shrink each cover of the produced HD to a minimal set of edges covering its bag")
+	rootDepth := flagSet.Bool("rootdepth", false, "re-root the produced HD to minimise its depth")
+	rootVertices := flagSet.String("rootvertices", "", "comma-separated list of vertices to put into the root of the produced HD")
+
+	// cost flags
+	weightsPath := flagSet.String("weights", "", "file of edge weights (e.g. cardinalities), one \"<edge name> <weight>\" per line")
+
+	// treewidth flags
+	tw := flagSet.Bool("tw", false, "compute tree decompositions, with -width giving the treewidth (bag size minus one)")
+	twHeuristic := flagSet.Bool("twheuristic", false, "use the min-fill heuristic for -tw, instead of searching for a given width")
+	gr := flagSet.Bool("gr", false, "Use PACE 2017 format for graphs (see pacechallenge.org/2017/treewidth/)")
+	tdPath := flagSet.String("td", "", "Output the tree decomposition produced with -tw into the specified file, in PACE 2017 format")
+
+	enum := flagSet.Int("enum", 0, "enumerate up to the given number of distinct HDs with LogKDecomp, or all if negative")
+	equiv := flagSet.String("equiv", "covers", "equivalence used by -enum to skip duplicates: covers, bags or unrooted")
+	portfolio := flagSet.String("portfolio", "", "race a comma-separated list of configurations, e.g. \"logk,hybrid,hybrid2+h1\",\n\t"+
+		"returning the first result (algorithms logk, hybrid, hybrid1 to hybrid4, detk and detk-subedge,\n\t"+
+		"optionally with +h1 to +h4 for -heuristic)")
+	split := flagSet.Bool("split", false, "decompose the connected components of the hypergraph separately and concurrently, reporting the width of each")
+	cheapest := flagSet.Bool("cheapest", false, "search for the HD minimising the cost of its most expensive node, using -weights or the relation sizes in -db")
+
+	flagSet.Usage = func() { decomposeUsage(flagSet) }
+	if code, stop := parseFlags(flagSet, args); stop {
+		return code
 	}
 
 	// Output usage message if graph and width not specified
-	if parseError != nil || *graphPath == "" || (*width <= 0 && !*exact) {
-		out := fmt.Sprint("Usage of log-k-decomp:")
-		fmt.Fprintln(os.Stderr, out)
-		flagSet.VisitAll(func(f *flag.Flag) {
-			if f.Name != "width" && f.Name != "graph" && f.Name != "exact" {
-				return
-			}
-			s := fmt.Sprintf("%T", f.Value) // used to get type of flag
-			if s[6:len(s)-5] != "bool" {
-				fmt.Printf("  -%-10s \t<%s>\n", f.Name, s[6:len(s)-5])
-			} else {
-				fmt.Printf("  -%-10s \n", f.Name)
-			}
-			fmt.Println("\t" + f.Usage)
-		})
-
-		fmt.Println("\nAlgorithm Choice: ")
-		flagSet.VisitAll(func(f *flag.Flag) {
-			if f.Name != "logk" && f.Name != "logkHybrid" {
-				return
-			}
-			s := fmt.Sprintf("%T", f.Value) // used to get type of flag
-			if s[6:len(s)-5] != "bool" {
-				fmt.Printf("  -%-10s \t<%s>\n", f.Name, s[6:len(s)-5])
-			} else {
-				fmt.Printf("  -%-10s \n", f.Name)
-			}
-			fmt.Println("\t" + f.Usage)
-		})
-
-		fmt.Println("\nOptional Arguments: ")
-		flagSet.VisitAll(func(f *flag.Flag) {
-			if f.Name == "width" || f.Name == "graph" || f.Name == "exact" || f.Name == "logkHybrid" || f.Name == "logk" {
-				return
-			}
-			s := fmt.Sprintf("%T", f.Value) // used to get type of flag
-			if s[6:len(s)-5] != "bool" {
-				fmt.Printf("  -%-10s \t<%s>\n", f.Name, s[6:len(s)-5])
-			} else {
-				fmt.Printf("  -%-10s \n", f.Name)
-			}
-			fmt.Println("\t" + f.Usage)
-		})
-
-		return
+	if *graphPath == "" || (*width <= 0 && !*exact && !(*tw && *twHeuristic)) {
+		fmt.Fprint(os.Stderr, "The flags -graph and -width (or -exact) need to be specified.\n\n")
+		flagSet.Usage()
+		return exitError
+	}
+	if err := checkFlags(activeFlags(flagSet)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
 	}
 
 	// END Command-Line Argument Parsing
@@ -205,104 +271,419 @@ func main() {
 	}
 	logActive(*logging)
 
-	BalFactor := *balanceFactorFlag
-
 	runtime.GOMAXPROCS(*numCPUs)
 
-	dat, err := ioutil.ReadFile(*graphPath)
-	check(err)
+	deadline := time.Now().Add(*timeout) // shared by all documents
 
-	var parsedGraph Graph
+	dat, err := logk.ReadInput(*graphPath)
+	if err != nil {
+		fmt.Println(err)
+		return exitError
+	}
 
-	if !*pace {
-		parsedGraph, _ = lib.GetGraph(string(dat))
-	} else {
-		parsedGraph = lib.GetGraphPACE(string(dat))
+	pipeline, err := preprocessingPipeline(*pre, *typeC, *gyö, *hingeFlag)
+	if err != nil {
+		fmt.Println(err)
+		return exitError
+	}
+	configs, err := parsePortfolio(*portfolio)
+	if err != nil {
+		fmt.Println(err)
+		return exitError
+	}
+	balance, err := balanceOptions(*balanceRatio, *balanceMeasure, *balanceFactorFlag)
+	if err != nil {
+		fmt.Println(err)
+		return exitError
 	}
 
-	originalGraph := parsedGraph
+	opts := options{
+		width:            *width,
+		exact:            *exact,
+		logK:             *logK,
+		detK:             *detK,
+		detKSubEdge:      *detKSubEdge,
+		detKParallel:     *detKParallel,
+		logKHybridCustom: *logKHybridCustom,
+		meta:             *meta,
+		useHeuristic:     *useHeuristic,
+		pipeline:         pipeline,
+		hinge:            len(pipeline) > 0 && pipeline[len(pipeline)-1] == "hinge",
+		balFactor:        *balanceFactorFlag,
+		balance:          balance,
+		bench:            *bench,
+		gml:              *gml,
+		pace:             *pace,
+		dbPath:           *dbPath,
+		eval:             *eval,
+		boolean:          *boolean,
+		count:            *count,
+		free:             *free,
+		sqlPath:          *sqlPath,
+		sqlDialect:       *sqlDialect,
+		sqlMapping:       *sqlMapping,
+		weightsPath:      *weightsPath,
+		cheapest:         *cheapest,
+		enum:             *enum,
+		redundant:        *normalise || *redundant,
+		minCover:         *normalise || *minCover,
+		rootDepth:        *rootDepth,
+		rootVertices:     *rootVertices,
+		tw:               *tw,
+		twHeuristic:      *twHeuristic,
+		gr:               *gr,
+		tdPath:           *tdPath,
+		equiv:            *equiv,
+		split:            *split,
+		portfolio:        configs,
+		memLimit:         uint64(*memLimit) << 20,
+		timeout:          *timeout,
+	}
 
-	if !*bench { // skip any output if bench flag is set
-		log.Println("BIP: ", parsedGraph.GetBIP())
+	code := exitOK
+	documents := logk.SplitDocuments(string(dat), *pace || *gr)
+	for i := range documents {
+		docOpts := opts
+		docOpts.label = *graphPath
+		if len(documents) > 1 {
+			docOpts.label = fmt.Sprintf("%s (document %d of %d)", *graphPath, i+1, len(documents))
+			fmt.Println("\n=== Document", i+1, "of", len(documents), "===")
+
+			if len(opts.gml) > 0 {
+				docOpts.gml = fmt.Sprintf("%s_%d.gml", strings.TrimSuffix(opts.gml, ".gml"), i+1)
+			}
+			if len(opts.tdPath) > 0 {
+				docOpts.tdPath = fmt.Sprintf("%s_%d.td", strings.TrimSuffix(opts.tdPath, ".td"), i+1)
+			}
+		}
+
+		code = worseExit(code, decomposeDocument(documents[i], docOpts, deadline))
 	}
 
-	var reducedGraph Graph
+	return code
+}
 
-	var times []labelTime
+// decomposeDocument decomposes a single document of the input. Each document gets a memory guard of its own, so
+// that exceeding the limit on one of them doesn't stop the following ones, while the timeout ends at the deadline.
+func decomposeDocument(document string, opts options, deadline time.Time) int {
+	if opts.memLimit > 0 {
+		opts.memory = logk.NewMemoryGuard(opts.memLimit)
+		opts.memory.Start(50 * time.Millisecond)
+		defer opts.memory.Close()
+	}
+	opts.stop = opts.memory.Flag().Child()
+	if opts.timeout > 0 {
+		timer := time.AfterFunc(time.Until(deadline), opts.stop.Stop)
+		defer timer.Stop()
+	}
 
-	// Sorting Edges to find separators faster
-	if *useHeuristic > 0 {
-		var heuristicMessage string
+	if opts.tw {
+		return decomposeTW(document, opts)
+	}
+	return decompose(document, opts)
+}
 
-		start := time.Now()
-		switch *useHeuristic {
-		case 1:
-			parsedGraph.Edges = lib.GetDegreeOrder(parsedGraph.Edges)
-			heuristicMessage = "Using degree ordering as a heuristic"
-			break
-		case 2:
-			parsedGraph.Edges = lib.GetMaxSepOrder(parsedGraph.Edges)
-			heuristicMessage = "Using max separator ordering as a heuristic"
-			break
-		case 3:
-			parsedGraph.Edges = lib.GetMSCOrder(parsedGraph.Edges)
-			heuristicMessage = "Using MSC ordering as a heuristic"
-			break
-		case 4:
-			parsedGraph.Edges = lib.GetEdgeDegreeOrder(parsedGraph.Edges)
-			heuristicMessage = "Using edge degree ordering as a heuristic"
-			break
+// decomposeUsage prints the flags of the decompose command, starting with the required ones and the choice of
+// the algorithm
+func decomposeUsage(flagSet *flag.FlagSet) {
+	w := flagSet.Output()
+	required := map[string]bool{"graph": true, "width": true, "exact": true}
+	algorithms := map[string]bool{"logk": true, "detk": true, "detk-subedge": true, "logkHybridCustom": true,
+		"portfolio": true}
+
+	fmt.Fprintln(w, "Usage of log-k-decomp decompose: -graph <file> (-width <k> | -exact) [flags]")
+	printFlags(w, flagSet, func(name string) bool { return required[name] })
+
+	fmt.Fprintln(w, "\nAlgorithm Choice: ")
+	printFlags(w, flagSet, func(name string) bool { return algorithms[name] })
+
+	fmt.Fprintln(w, "\nOptional Arguments: ")
+	printFlags(w, flagSet, func(name string) bool { return !required[name] && !algorithms[name] })
+}
+
+// algorithmFlags are the flags choosing an algorithm, of which at most one can be given
+var algorithmFlags = []string{"logk", "detk", "detk-subedge", "logkHybridCustom", "portfolio"}
+
+// flagConflicts lists pairs of flags which can't be combined, as one of them would be ignored or contradict the
+// other
+var flagConflicts = [][2]string{
+	{"bench", "log"},
+	{"pace", "gr"},
+	{"logk", "detk-parallel"},
+	{"portfolio", "heuristic"},
+	{"enum", "portfolio"},
+	{"enum", "split"},
+	{"enum", "cheapest"},
+}
+
+// twIgnored lists the flags which have no effect when computing tree decompositions
+var twIgnored = []string{"logk", "detk", "detk-subedge", "detk-parallel", "logkHybridCustom", "portfolio", "split",
+	"enum", "heuristic", "pre", "t", "g", "h", "weights", "cheapest", "db", "eval", "boolean", "count", "sql",
+	"balance", "balmeasure", "mem"}
+
+// flagRequirements lists flags which only have an effect together with another one
+var flagRequirements = [][2]string{
+	{"twheuristic", "tw"},
+	{"td", "tw"},
+	{"meta", "logkHybridCustom"},
+	{"equiv", "enum"},
+	{"sqldialect", "sql"},
+	{"sqlmap", "sql"},
+	{"eval", "db"},
+	{"boolean", "db"},
+	{"count", "db"},
+}
+
+// checkFlags rejects combinations of the given flags which would be silently ignored or contradict each other
+func checkFlags(active map[string]bool) error {
+	var chosen []string
+	for _, name := range algorithmFlags {
+		if active[name] {
+			chosen = append(chosen, "-"+name)
 		}
-		d := time.Now().Sub(start)
-		msec := d.Seconds() * float64(time.Second/time.Millisecond)
-		times = append(times, labelTime{time: msec, label: "Heuristic"})
+	}
+	if len(chosen) > 1 {
+		return fmt.Errorf("only one algorithm may be chosen at a time, got %s", strings.Join(chosen, " and "))
+	}
 
-		if !*bench {
-			fmt.Println(heuristicMessage)
-			fmt.Printf("Time for heuristic: %.5f ms\n", msec)
-			fmt.Printf("Ordering: %v\n", parsedGraph.String())
+	for _, pair := range flagConflicts {
+		if active[pair[0]] && active[pair[1]] {
+			return fmt.Errorf("the flags -%s and -%s can't be combined", pair[0], pair[1])
 		}
 	}
-	var removalMap map[int][]int
-	// Performing Type Collapse
-	if *typeC {
-		count := 0
-		reducedGraph, removalMap, count = parsedGraph.TypeCollapse()
-		parsedGraph = reducedGraph
-		if !*bench { // be silent when benchmarking
-			fmt.Println("\n\n", *graphPath)
-			fmt.Println("Graph after Type Collapse:")
-			for _, e := range reducedGraph.Edges.Slice() {
-				fmt.Printf("%v %v\n", e, Edge{Vertices: e.Vertices})
+	if active["tw"] {
+		for _, name := range twIgnored {
+			if active[name] {
+				return fmt.Errorf("the flag -%s has no effect with -tw", name)
 			}
-			fmt.Print("Removed ", count, " vertex/vertices\n\n")
 		}
 	}
+	for _, pair := range flagRequirements {
+		if active[pair[0]] && !active[pair[1]] {
+			return fmt.Errorf("the flag -%s needs -%s", pair[0], pair[1])
+		}
+	}
+	if active["cheapest"] && !active["weights"] && !active["db"] {
+		return errors.New("the flag -cheapest needs -weights or -db")
+	}
 
-	var ops []lib.GYÖReduct
-	// Performing GYÖ reduction
-	if *gyö {
+	return nil
+}
 
-		if *typeC {
-			reducedGraph, ops = reducedGraph.GYÖReduct()
-		} else {
-			reducedGraph, ops = parsedGraph.GYÖReduct()
+// options collects the settings used for the decomposition of a single hypergraph
+type options struct {
+	label            string // used to identify the input in the output
+	width            int
+	exact            bool
+	logK             bool
+	detK             bool
+	detKSubEdge      bool
+	detKParallel     bool // explore covers and components of DetKDecomp concurrently
+	logKHybridCustom int
+	meta             int
+	useHeuristic     int
+	pipeline         []string // preprocessing steps, in order
+	hinge            bool
+	balFactor        int
+	balance          logk.Balance // if set, replaces balFactor
+	bench            bool
+	gml              string
+	pace             bool
+	dbPath           string
+	eval             bool
+	boolean          bool
+	count            bool
+	free             string
+	sqlPath          string
+	sqlDialect       string
+	sqlMapping       string
+	weightsPath      string
+	cheapest         bool
+	enum             int
+	redundant        bool
+	minCover         bool
+	rootDepth        bool
+	rootVertices     string
+	tw               bool
+	twHeuristic      bool
+	gr               bool
+	tdPath           string
+	equiv            string
+	split            bool
+	portfolio        []portfolioConfig
+	memLimit         uint64            // in bytes, if positive a memory guard is started for each document
+	workers          int               // if positive, the workers searching for separators, instead of one per CPU
+	memory           *logk.MemoryGuard // if set, the searches degrade and then stop as memory runs out
+	stop             *logk.StopFlag    // stopped by the timeout or the memory limit
+	timeout          time.Duration
+}
+
+// normaliseDecomp applies the normalisation selected in the options, and reports the changes made
+func normaliseDecomp(decomp Decomp, ctx runContext, opts options) Decomp {
+	if reflect.DeepEqual(decomp, Decomp{}) || (!opts.redundant && !opts.minCover && !opts.rootDepth && opts.rootVertices == "") {
+		return decomp
+	}
+
+	rootVertices, err := parseVertices(ctx, opts.rootVertices)
+	if err != nil {
+		fmt.Println("Skipping normalisation:", err)
+		return decomp
+	}
+
+	decomp.RestoreSubedges()
+	decomp, report := logk.Normalise(decomp, logk.NormaliseOptions{
+		RemoveRedundant: opts.redundant,
+		MinimiseCovers:  opts.minCover,
+		MinimiseDepth:   opts.rootDepth,
+		RootVertices:    rootVertices,
+	})
+
+	fmt.Println("Normalisation:", report)
+	if len(report.MissingAtRoot) > 0 {
+		fmt.Println("Not at the root:", ctx.encoding.Vertices(report.MissingAtRoot))
+	}
+
+	return decomp
+}
+
+// enumerateDecomps prints up to opts.enum distinct decompositions of width K, as found by LogKDecomp, returning
+// the exit code
+func enumerateDecomps(graph Graph, K int, times []labelTime, ctx runContext, opts options, weights logk.Weights) int {
+	equiv, err := logk.GetEquivalence(opts.equiv)
+	if err != nil {
+		fmt.Println(err)
+		return exitError
+	}
+
+	opts.balance.Weights = weights
+	logK := logk.LogKDecomp{Graph: graph, K: K, BalFactor: opts.balFactor, Balance: opts.balance, Stop: opts.stop,
+		Memory: opts.memory}
+	logK.SetGenerator(lib.ParallelSearchGen{})
+	logK.SetCost(weights, 0)
+
+	fmt.Println("Used algorithm: " + logK.Name())
+	fmt.Println("Enumerating HDs of width", K)
+
+	start := time.Now()
+	found := 0
+
+	it := logK.Iterator(equiv)
+	defer it.Close()
+	for (opts.enum < 0 || found < opts.enum) && it.Next() {
+		found++
+		decomp := ctx.restore(it.Decomp(), graph)
+		decomp.RestoreSubedges()
+		decomp = normaliseDecomp(decomp, ctx, opts)
+
+		fmt.Println("\nDecomposition", found, "\n", ctx.encoding.Decomp(decomp))
+		fmt.Println("Width: ", decomp.CheckWidth())
+		fmt.Println("Correct: ", decomp.Correct(ctx.original))
+		if weights != nil {
+			total, max := weights.Annotate(&decomp)
+			fmt.Printf("Estimated cost: %.2f (most expensive node: %.2f)\n", total, max)
 		}
 
-		parsedGraph = reducedGraph
-		if !*bench { // be silent when benchmarking
-			fmt.Println("Graph after GYÖ:")
-			fmt.Println(reducedGraph)
-			fmt.Println("Reductions:")
-			fmt.Print(ops, "\n\n")
+		if len(opts.gml) > 0 {
+			f, err := os.Create(fmt.Sprintf("%s_hd%d.gml", strings.TrimSuffix(opts.gml, ".gml"), found))
+			check(err)
+			f.WriteString(ctx.encoding.GML(decomp))
+			f.Close()
 		}
+	}
+
+	msec := time.Since(start).Seconds() * float64(time.Second/time.Millisecond)
+	times = append(times, labelTime{time: msec, label: "Enumeration"})
+
+	fmt.Println("\nNumber of decompositions: ", found)
+
+	var sumTotal float64
+	for _, time := range times {
+		sumTotal = sumTotal + time.time
+	}
+	fmt.Printf("Time: %.5f ms\n", sumTotal)
 
+	fmt.Println("Time Composition: ")
+	for _, time := range times {
+		fmt.Println(time)
 	}
 
+	switch {
+	case found > 0:
+		if opts.stop.Stopped() { // the decompositions found so far are still valid
+			reportStopped(opts)
+		}
+		return exitOK
+	case opts.stop.Stopped():
+		return reportStopped(opts)
+	}
+	return exitNotFound
+}
+
+// costSolver is implemented by the algorithms which can take the cost of nodes into account
+type costSolver interface {
+	SetCost(weights logk.Weights, bound float64)
+}
+
+// reportStopped explains why the search was stopped, returning the exit code for it
+func reportStopped(opts options) int {
+	if opts.memory.Exceeded() {
+		fmt.Println("Memory limit exceeded, search stopped:", opts.memory)
+	} else {
+		fmt.Printf("Timeout after %v, search stopped\n", opts.timeout)
+	}
+	return exitStopped
+}
+
+// decompose parses a single hypergraph, and searches for a decomposition of it with the given options, returning
+// the exit code
+func decompose(input string, opts options) int {
+	var parsedGraph Graph
+	var encoding logk.Encoding
+	var err error
+
+	if !opts.pace {
+		parsedGraph, encoding, err = logk.GetGraph(input)
+	} else {
+		parsedGraph, encoding, err = logk.GetGraphPACE(input)
+	}
+	if err != nil {
+		fmt.Println("Skipping", opts.label+":", err)
+		return exitError
+	}
+
+	ctx := runContext{encoding: encoding, original: parsedGraph}
+	width := opts.width
+
+	if !opts.bench { // skip any output if bench flag is set
+		log.Println("BIP: ", parsedGraph.GetBIP())
+	}
+
+	var times []labelTime
+
+	// Sorting Edges to find separators faster
+	if opts.useHeuristic > 0 {
+		var heuristicMessage string
+
+		start := time.Now()
+		parsedGraph.Edges, heuristicMessage = orderEdges(parsedGraph.Edges, opts.useHeuristic)
+		d := time.Now().Sub(start)
+		msec := d.Seconds() * float64(time.Second/time.Millisecond)
+		times = append(times, labelTime{time: msec, label: "Heuristic"})
+
+		if !opts.bench {
+			fmt.Println(heuristicMessage)
+			fmt.Printf("Time for heuristic: %.5f ms\n", msec)
+			fmt.Printf("Ordering: %v\n", encoding.Graph(parsedGraph))
+		}
+	}
+	// Performing the preprocessing steps
+	parsedGraph, preTimes := preprocess(parsedGraph, &ctx, opts)
+	times = append(times, preTimes...)
+
 	var hinget lib.Hingetree
 	var msecHinge float64
 
-	if *hingeFlag {
+	if opts.hinge {
 		startHinge := time.Now()
 
 		hinget = lib.GetHingeTree(parsedGraph)
@@ -311,27 +692,140 @@ func main() {
 		msecHinge = dHinge.Seconds() * float64(time.Second/time.Millisecond)
 		times = append(times, labelTime{time: msecHinge, label: "Hingetree"})
 
-		if !*bench {
+		if !opts.bench {
 			fmt.Println("Produced Hingetree: ")
 			fmt.Println(hinget)
 		}
 	}
 
+	weights, err := loadWeights(&ctx, opts)
+	if err != nil {
+		fmt.Println("Couldn't determine edge weights:", err)
+		return exitError
+	}
+
+	if opts.enum != 0 && !opts.exact { // the width is known, go straight to the enumeration
+		return enumerateDecomps(parsedGraph, width, times, ctx, opts, weights)
+	}
+
+	var decomp Decomp
+	var algorithm string
+	var searchTimes []labelTime
+
+	var comps []Graph
+	if opts.split {
+		comps = logk.ConnectedComponents(parsedGraph)
+	}
+	if len(comps) > 1 {
+		decomp, width, algorithm, searchTimes, err = searchComponents(parsedGraph, comps, width, ctx, opts, weights)
+	} else {
+		var hingePtr *lib.Hingetree
+		if opts.hinge {
+			hingePtr = &hinget
+		}
+		decomp, width, algorithm, searchTimes, err = solve(parsedGraph, hingePtr, width, opts, weights)
+	}
+	if err != nil {
+		fmt.Println(err)
+		return exitError
+	}
+	if opts.stop.Stopped() && reflect.DeepEqual(decomp, Decomp{}) {
+		return reportStopped(opts)
+	}
+	times = append(times, searchTimes...)
+	if opts.memory.Evictions() > 0 && !opts.bench {
+		fmt.Println("Memory:", opts.memory)
+	}
+
+	if opts.enum != 0 {
+		return enumerateDecomps(parsedGraph, width, times, ctx, opts, weights)
+	}
+
+	decomp = ctx.restore(decomp, parsedGraph)
+	decomp = normaliseDecomp(decomp, ctx, opts)
+
+	var total, max float64
+	if weights != nil && !reflect.DeepEqual(decomp, Decomp{}) {
+		total, max = weights.Annotate(&decomp)
+	}
+
+	outputStanza(algorithm, decomp, times, ctx, opts.gml, width, false)
+	if weights != nil && !reflect.DeepEqual(decomp, Decomp{}) {
+		fmt.Printf("Estimated cost: %.2f (most expensive node: %.2f)\n", total, max)
+	}
+
+	decomp.RestoreSubedges()
+	if opts.eval || opts.boolean || opts.count {
+		evaluateQuery(decomp, ctx, opts)
+	}
+	if len(opts.sqlPath) > 0 {
+		exportSQL(decomp, ctx, opts)
+	}
+
+	if reflect.DeepEqual(decomp, Decomp{}) {
+		return exitNotFound
+	}
+	return exitOK
+}
+
+// orderEdges sorts the edges with the given heuristic, to find separators faster. The edges are sorted in place.
+func orderEdges(edges lib.Edges, heuristic int) (lib.Edges, string) {
+	switch heuristic {
+	case 1:
+		return lib.GetDegreeOrder(edges), "Using degree ordering as a heuristic"
+	case 2:
+		return lib.GetMaxSepOrder(edges), "Using max separator ordering as a heuristic"
+	case 3:
+		return lib.GetMSCOrder(edges), "Using MSC ordering as a heuristic"
+	case 4:
+		return lib.GetEdgeDegreeOrder(edges), "Using edge degree ordering as a heuristic"
+	}
+
+	return edges, ""
+}
+
+// solve searches for a decomposition of the graph, either with the algorithm chosen in the options, or by racing
+// the configurations of the portfolio
+func solve(graph Graph, hinget *lib.Hingetree, width int, opts options,
+	weights logk.Weights) (Decomp, int, string, []labelTime, error) {
+	if len(opts.portfolio) > 0 {
+		return searchPortfolio(graph, width, opts, weights)
+	}
+
+	solver, err := newSolver(graph, width, opts, weights, opts.stop)
+	if err != nil {
+		return Decomp{}, width, "", nil, err
+	}
+
+	decomp, width, times := search(solver, graph, hinget, width, opts, weights, opts.stop)
+	return decomp, width, solver.Name(), times, nil
+}
+
+// newSolver sets up the algorithm chosen in the options, to search for decompositions of the graph until the
+// stop flag is set
+func newSolver(graph Graph, width int, opts options, weights logk.Weights, stop *logk.StopFlag) (algo.Algorithm,
+	error) {
 	var solver algo.Algorithm
+	opts.balance.Weights = weights
 
 	// Check for multiple flags
 	chosen := 0
 
 	// LogkHybrid Default
-	if !*logK && *logKHybridCustom == 0 {
-		logKHyb := LogKHybrid{
-			Graph:     parsedGraph,
-			K:         *width,
-			BalFactor: BalFactor,
+	if !opts.logK && opts.logKHybridCustom == 0 && !opts.detK && !opts.detKSubEdge {
+		logKHyb := logk.LogKHybrid{
+			Graph:     graph,
+			K:         width,
+			BalFactor: opts.balFactor,
+			Balance:   opts.balance,
+			Stop:      stop,
+			Parallel:  opts.detKParallel,
+			Memory:    opts.memory,
+			Workers:   opts.workers,
 		}
 		logKHyb.Size = 300 // use the default case
 
-		var pred HybridPredicate
+		var pred logk.HybridPredicate
 
 		pred = logKHyb.ETimesKDivAvgEdgePred // use the default method
 
@@ -341,28 +835,55 @@ func main() {
 		chosen++
 	}
 
-	if *logK {
-		logK := LogKDecomp{
-			Graph:     parsedGraph,
-			K:         *width,
-			BalFactor: BalFactor,
+	if opts.logK {
+		logK := logk.LogKDecomp{
+			Graph:     graph,
+			K:         width,
+			BalFactor: opts.balFactor,
+			Balance:   opts.balance,
+			Stop:      stop,
+			Memory:    opts.memory,
+			Workers:   opts.workers,
 		}
 		solver = &logK
 		chosen++
 	}
 
+	if opts.detK || opts.detKSubEdge {
+		detK := logk.DetKDecomp{
+			Graph:     graph,
+			K:         width,
+			BalFactor: opts.balFactor,
+			SubEdge:   opts.detKSubEdge,
+			Stop:      stop,
+			Parallel:  opts.detKParallel,
+			Memory:    opts.memory,
+			Workers:   opts.workers,
+		}
+		solver = &detK
+		chosen++
+		if opts.detK && opts.detKSubEdge {
+			chosen++
+		}
+	}
+
 	// LogkHybrid Custom - To be used if you know what you are doing
-	if *logKHybridCustom > 0 {
-		logKHyb := LogKHybrid{
-			Graph:     parsedGraph,
-			K:         *width,
-			BalFactor: BalFactor,
+	if opts.logKHybridCustom > 0 {
+		logKHyb := logk.LogKHybrid{
+			Graph:     graph,
+			K:         width,
+			BalFactor: opts.balFactor,
+			Balance:   opts.balance,
+			Stop:      stop,
+			Parallel:  opts.detKParallel,
+			Memory:    opts.memory,
+			Workers:   opts.workers,
 		}
-		logKHyb.Size = *meta
+		logKHyb.Size = opts.meta
 
-		var pred HybridPredicate
+		var pred logk.HybridPredicate
 
-		switch *logKHybridCustom {
+		switch opts.logKHybridCustom {
 		case 1:
 			pred = logKHyb.NumberEdgesPred
 		case 2:
@@ -381,65 +902,77 @@ func main() {
 	}
 
 	if chosen > 1 {
-		fmt.Println("Only one algorithm may be chosen at a time. Make up your mind.")
-		return
+		return nil, errors.New("Only one algorithm may be chosen at a time. Make up your mind.")
+	}
+	if solver == nil {
+		return nil, errors.New("No algorithm or procedure selected.")
 	}
 
-	if solver != nil {
+	solver.SetGenerator(lib.ParallelSearchGen{})
+	if weights != nil {
+		solver.(costSolver).SetCost(weights, 0)
+	}
 
-		solver.SetGenerator(lib.ParallelSearchGen{})
+	return solver, nil
+}
 
-		var decomp Decomp
-		start := time.Now()
+// search looks for a decomposition of the graph with the solver, using the hinge tree if given. In exact mode,
+// the smallest width is searched for, and the width of the result is returned. The search gives up once the stop
+// flag is set, which has to be the one of the solver.
+func search(solver algo.Algorithm, graph Graph, hinget *lib.Hingetree, width int, opts options,
+	weights logk.Weights, stop *logk.StopFlag) (Decomp, int, []labelTime) {
+	var times []labelTime
 
-		if *exact {
-			solved := false
-			k := 1
-			for ; !solved; k++ {
-				solver.SetWidth(k)
+	findDecomp := func() Decomp {
+		if hinget != nil {
+			return hinget.DecompHinge(solver, graph)
+		}
+		return solver.FindDecomp()
+	}
 
-				if *hingeFlag {
-					decomp = hinget.DecompHinge(solver, parsedGraph)
-				} else {
-					decomp = solver.FindDecomp()
-				}
+	var decomp Decomp
+	start := time.Now()
 
-				solved = decomp.Correct(parsedGraph)
-			}
-			*width = k - 1 // for correct output
-		} else {
-			if *hingeFlag {
-				decomp = hinget.DecompHinge(solver, parsedGraph)
-			} else {
-				decomp = solver.FindDecomp()
-			}
-		}
+	if graph.Edges.Len() == 0 { // fully reduced by preprocessing, the decomposition is built when restoring it
+		return decomp, 1, times
+	}
 
-		d := time.Now().Sub(start)
-		msec := d.Seconds() * float64(time.Second/time.Millisecond)
-		times = append(times, labelTime{time: msec, label: "Decomposition"})
+	if opts.exact {
+		solved := false
+		k := 1
+		for ; !solved && !stop.Stopped(); k++ {
+			solver.SetWidth(k)
 
-		if !reflect.DeepEqual(decomp, Decomp{}) || (len(ops) > 0 && parsedGraph.Edges.Len() == 0) {
-			var result bool
-			decomp.Root, result = decomp.Root.RestoreGYÖ(ops)
-			if !result {
-				fmt.Println("Partial decomp:", decomp.Root)
-				log.Panicln("GYÖ reduction failed")
-			}
-			decomp.Root, result = decomp.Root.RestoreTypes(removalMap)
-			if !result {
-				fmt.Println("Partial decomp:", decomp.Root)
-				log.Panicln("Type Collapse reduction failed")
-			}
+			decomp = findDecomp()
+
+			solved = decomp.Correct(graph)
 		}
+		width = k - 1 // for correct output
+	} else {
+		decomp = findDecomp()
+	}
 
-		if !reflect.DeepEqual(decomp, Decomp{}) {
-			decomp.Graph = originalGraph
+	d := time.Now().Sub(start)
+	msec := d.Seconds() * float64(time.Second/time.Millisecond)
+	times = append(times, labelTime{time: msec, label: "Decomposition"})
+
+	// lower the bound on the cost of nodes, until no cheaper decomposition exists
+	if opts.cheapest && weights != nil && !reflect.DeepEqual(decomp, Decomp{}) && opts.enum == 0 {
+		start = time.Now()
+		for {
+			_, max := weights.Annotate(&decomp)
+			solver.(costSolver).SetCost(weights, math.Nextafter(max, 0))
+
+			cheaper := findDecomp()
+			if reflect.DeepEqual(cheaper, Decomp{}) {
+				break
+			}
+			decomp = cheaper
 		}
-		outputStanza(solver.Name(), decomp, times, originalGraph, *gml, *width, false)
 
-		return
+		msec := time.Since(start).Seconds() * float64(time.Second/time.Millisecond)
+		times = append(times, labelTime{time: msec, label: "Cost optimisation"})
 	}
 
-	fmt.Println("No algorithm or procedure selected.")
+	return decomp, width, times
 }
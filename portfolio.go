@@ -0,0 +1,155 @@
+package main
+
+// Portfolio mode, racing several configurations of the algorithms on the same input
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+// portfolioConfig is a configuration raced in portfolio mode
+type portfolioConfig struct {
+	name             string
+	logK             bool
+	logKHybridCustom int
+	detK             bool
+	detKSubEdge      bool
+	useHeuristic     int
+}
+
+// parsePortfolio reads a comma-separated list of configurations. Each consists of an algorithm, which is logk,
+// hybrid (the default hybridisation), hybrid1 to hybrid4 (those of -logkHybridCustom), detk or detk-subedge,
+// optionally followed by "+h1" to "+h4" for one of the edge orderings of -heuristic.
+func parsePortfolio(list string) ([]portfolioConfig, error) {
+	var output []portfolioConfig
+
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		parts := strings.Split(name, "+")
+		config := portfolioConfig{name: name}
+
+		switch algorithm := parts[0]; {
+		case algorithm == "logk":
+			config.logK = true
+		case algorithm == "hybrid":
+		case algorithm == "detk":
+			config.detK = true
+		case algorithm == "detk-subedge":
+			config.detKSubEdge = true
+		case strings.HasPrefix(algorithm, "hybrid"):
+			custom, err := strconv.Atoi(strings.TrimPrefix(algorithm, "hybrid"))
+			if err != nil || custom < 1 || custom > 4 {
+				return nil, fmt.Errorf("unknown algorithm %q in portfolio configuration %q", algorithm, name)
+			}
+			config.logKHybridCustom = custom
+		default:
+			return nil, fmt.Errorf("unknown algorithm %q in portfolio configuration %q", algorithm, name)
+		}
+
+		for _, part := range parts[1:] {
+			heuristic, err := strconv.Atoi(strings.TrimPrefix(part, "h"))
+			if !strings.HasPrefix(part, "h") || err != nil || heuristic < 1 || heuristic > 4 {
+				return nil, fmt.Errorf("unknown setting %q in portfolio configuration %q", part, name)
+			}
+			config.useHeuristic = heuristic
+		}
+
+		output = append(output, config)
+	}
+
+	return output, nil
+}
+
+// portfolioResult holds the outcome of a single configuration of the portfolio
+type portfolioResult struct {
+	config    portfolioConfig
+	decomp    Decomp
+	width     int
+	algorithm string
+	times     []labelTime
+	err       error
+}
+
+// searchPortfolio races the configurations of the portfolio on the graph, sharing the CPUs available. The first
+// configuration to finish wins, whether it found a decomposition or showed that none exists, and the others are
+// stopped.
+func searchPortfolio(graph Graph, width int, opts options,
+	weights logk.Weights) (Decomp, int, string, []labelTime, error) {
+	start := time.Now()
+	stop := opts.stop.Child()                             // also stopped by the timeout or the memory limit
+	ch := make(chan portfolioResult, len(opts.portfolio)) // buffered, so stopped configurations can finish
+
+	// the workers are split between the configurations, so that together they use the CPUs available once
+	cpus := runtime.GOMAXPROCS(-1)
+
+	for i, config := range opts.portfolio {
+		workers := cpus / len(opts.portfolio)
+		if i < cpus%len(opts.portfolio) {
+			workers++
+		}
+		if workers < 1 {
+			workers = 1
+		}
+
+		go func(config portfolioConfig, workers int) {
+			out := portfolioResult{config: config}
+
+			configOpts := opts
+			configOpts.workers = workers
+			configOpts.logK = config.logK
+			configOpts.logKHybridCustom = config.logKHybridCustom
+			configOpts.detK = config.detK
+			configOpts.detKSubEdge = config.detKSubEdge
+
+			// each configuration orders its own copy of the edges
+			configGraph := graph
+			configGraph.Edges = lib.NewEdges(append([]Edge{}, graph.Edges.Slice()...))
+			configGraph.Edges, _ = orderEdges(configGraph.Edges, config.useHeuristic)
+
+			solver, err := newSolver(configGraph, width, configOpts, weights, stop)
+			if err != nil {
+				out.err = err
+				ch <- out
+				return
+			}
+			out.algorithm = solver.Name()
+
+			var hinget *lib.Hingetree
+			if opts.hinge {
+				tree := lib.GetHingeTree(configGraph)
+				hinget = &tree
+			}
+
+			out.decomp, out.width, out.times = search(solver, configGraph, hinget, width, configOpts, weights, stop)
+			ch <- out
+		}(config, workers)
+	}
+
+	winner := <-ch
+	stop.Stop()
+
+	if winner.err != nil {
+		return Decomp{}, width, "", nil, winner.err
+	}
+
+	msec := time.Since(start).Seconds() * float64(time.Second/time.Millisecond)
+	if !reflect.DeepEqual(winner.decomp, Decomp{}) {
+		winner.decomp.Graph = graph
+		fmt.Printf("Portfolio winner: %s, found a decomposition after %.5f ms\n", winner.config.name, msec)
+	} else {
+		fmt.Printf("Portfolio winner: %s, found no decomposition after %.5f ms\n", winner.config.name, msec)
+	}
+
+	return winner.decomp, winner.width, fmt.Sprintf("%s (portfolio: %s)", winner.algorithm, winner.config.name),
+		winner.times, nil
+}
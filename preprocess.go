@@ -0,0 +1,83 @@
+package main
+
+// The preprocessing pipeline, applying reductions to the hypergraph before it is decomposed
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+// stepLabels names the preprocessing steps in the time composition
+var stepLabels = map[string]string{
+	"dedup":        "Duplicate edges",
+	"subsume":      "Subsumed edges",
+	"degree1":      "Degree-1 vertices",
+	"typecollapse": "Type Collapse",
+	"gyo":          "GYÖ",
+}
+
+// preprocessingPipeline determines the preprocessing steps, either from the list given via -pre, or from the
+// older flags -t, -g and -h, which stand for the pipeline typecollapse,gyo,hinge
+func preprocessingPipeline(pre string, typeC, gyö, hinge bool) ([]string, error) {
+	if pre == "" {
+		var output []string
+		if typeC {
+			output = append(output, "typecollapse")
+		}
+		if gyö {
+			output = append(output, "gyo")
+		}
+		if hinge {
+			output = append(output, "hinge")
+		}
+		return output, nil
+	}
+
+	if typeC || gyö || hinge {
+		return nil, errors.New("the flags -t, -g and -h can't be combined with -pre")
+	}
+
+	output, err := logk.ParsePipeline(pre)
+	if err != nil {
+		return nil, err
+	}
+	for i := range output {
+		if output[i] == "hinge" && i < len(output)-1 {
+			return nil, errors.New("the hinge step has to come last, as it splits the hypergraph for the search")
+		}
+	}
+
+	return output, nil
+}
+
+// preprocess applies the steps of the pipeline to the graph, keeping track of the reductions in the context. The
+// hinge step is left to the search. Each step is timed, and the change in size is reported.
+func preprocess(graph Graph, ctx *runContext, opts options) (Graph, []labelTime) {
+	var times []labelTime
+
+	for _, step := range opts.pipeline {
+		if step == "hinge" {
+			continue
+		}
+
+		start := time.Now()
+		reduced, reduction, err := logk.Preprocess(step, graph)
+		check(err)
+		msec := time.Since(start).Seconds() * float64(time.Second/time.Millisecond)
+		times = append(times, labelTime{time: msec, label: stepLabels[step]})
+
+		if !opts.bench { // be silent when benchmarking
+			fmt.Printf("Graph after %s: %d -> %d vertices, %d -> %d edges\n", stepLabels[step],
+				len(graph.Vertices()), len(reduced.Vertices()), graph.Edges.Len(), reduced.Edges.Len())
+			fmt.Print(ctx.encoding.Graph(reduced), "\n\n")
+		}
+
+		ctx.reductions = append(ctx.reductions, reduction)
+		graph = reduced
+	}
+
+	return graph, times
+}
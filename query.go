@@ -0,0 +1,181 @@
+package main
+
+// query.go uses a decomposition to evaluate the query it was computed for
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+// parseVertices determines the vertices given as a comma-separated list of their names, rejecting names of edges
+func parseVertices(ctx runContext, list string) ([]int, error) {
+	var output []int
+
+	if len(list) > 0 {
+		vertices := ctx.original.Vertices()
+		for _, name := range strings.Split(list, ",") {
+			v, ok := ctx.encoding.ID(strings.TrimSpace(name))
+			if !ok || !lib.Subset([]int{v}, vertices) {
+				return nil, fmt.Errorf("unknown vertex: %v", name)
+			}
+			output = append(output, v)
+		}
+	}
+
+	return output, nil
+}
+
+// freeVertices determines the free vertices of the query, as given by their names
+func freeVertices(ctx runContext, opts options) ([]int, error) {
+	return parseVertices(ctx, opts.free)
+}
+
+// evaluateQuery loads the relations of the query and evaluates it over the decomposition, printing either the
+// answers as CSV or just whether any answer exists
+func evaluateQuery(decomp Decomp, ctx runContext, opts options) {
+	if reflect.DeepEqual(decomp, Decomp{}) {
+		fmt.Println("No decomposition found, cannot evaluate query")
+		return
+	}
+	if opts.dbPath == "" {
+		fmt.Println("No database given, use the -db flag to specify the directory of the relations")
+		return
+	}
+
+	start := time.Now()
+	db := ctx.db
+	if db == nil {
+		var err error
+		db, err = logk.LoadDatabase(opts.dbPath, ctx.original, ctx.encoding)
+		if err != nil {
+			fmt.Println("Couldn't load database:", err)
+			return
+		}
+	}
+	msecLoad := time.Since(start).Seconds() * float64(time.Second/time.Millisecond)
+
+	free, err := freeVertices(ctx, opts)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	start = time.Now()
+	switch {
+	case opts.count:
+		count, err := logk.Count(decomp, ctx.original, db, free)
+		if err != nil {
+			fmt.Println("Counting failed:", err)
+			return
+		}
+		fmt.Println("\nNumber of answers: ", count)
+	case opts.boolean:
+		nonEmpty, err := logk.Decide(decomp, ctx.original, db)
+		if err != nil {
+			fmt.Println("Evaluation failed:", err)
+			return
+		}
+		fmt.Println("\nQuery has answers: ", nonEmpty)
+	default:
+		answers, err := logk.Evaluate(decomp, ctx.original, db, free)
+		if err != nil {
+			fmt.Println("Evaluation failed:", err)
+			return
+		}
+
+		fmt.Println("\nNumber of answers: ", answers.Len())
+		if !opts.bench {
+			answers.WriteCSV(os.Stdout, ctx.encoding)
+		}
+	}
+	msecEval := time.Since(start).Seconds() * float64(time.Second/time.Millisecond)
+
+	fmt.Println(labelTime{time: msecLoad, label: "Loading relations"})
+	fmt.Println(labelTime{time: msecEval, label: "Evaluation"})
+}
+
+// exportSQL writes the decomposition as an SQL query into the file given by the options
+func exportSQL(decomp Decomp, ctx runContext, opts options) {
+	if reflect.DeepEqual(decomp, Decomp{}) {
+		fmt.Println("No decomposition found, cannot export SQL")
+		return
+	}
+
+	dialect, err := logk.GetSQLDialect(opts.sqlDialect)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	var mapping logk.SQLMapping
+	if len(opts.sqlMapping) > 0 {
+		f, err := os.Open(opts.sqlMapping)
+		if err != nil {
+			fmt.Println("Couldn't read SQL mapping:", err)
+			return
+		}
+		mapping, err = logk.ReadSQLMapping(f)
+		f.Close()
+		if err != nil {
+			fmt.Println("Couldn't read SQL mapping:", err)
+			return
+		}
+	}
+
+	free, err := freeVertices(ctx, opts)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	query, err := logk.ToSQL(decomp, ctx.original, ctx.encoding, mapping, dialect, free, opts.boolean)
+	if err != nil {
+		fmt.Println("Couldn't export SQL:", err)
+		return
+	}
+
+	f, err := os.Create(opts.sqlPath)
+	check(err)
+	defer f.Close()
+
+	f.WriteString(query)
+	f.Sync()
+}
+
+// loadWeights determines the weights of the edges, either from the file given by the options or, when looking for
+// the cheapest decomposition or balancing by weights, from the sizes of the relations. Without either, no weights
+// are used.
+func loadWeights(ctx *runContext, opts options) (logk.Weights, error) {
+	if len(opts.weightsPath) > 0 {
+		f, err := os.Open(opts.weightsPath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		return logk.ReadWeights(f, ctx.original, ctx.encoding)
+	}
+
+	byWeights := opts.balance.Measure == logk.WeightMeasure
+	if (opts.cheapest || byWeights) && len(opts.dbPath) > 0 {
+		db, err := logk.LoadDatabase(opts.dbPath, ctx.original, ctx.encoding)
+		if err != nil {
+			return nil, err
+		}
+		ctx.db = db // keep the relations for the evaluation
+
+		return logk.WeightsFromDatabase(db), nil
+	}
+
+	if opts.cheapest || byWeights {
+		return nil, fmt.Errorf("no weights given, use -weights or -db")
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+// The serve command, running log-k-decomp as a long-lived HTTP service
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/cem-okulmus/log-k-decomp/service"
+)
+
+// runServe implements the serve command, with the arguments following it
+func runServe(args []string) int {
+	flagSet := flag.NewFlagSet("serve", flag.ContinueOnError)
+	addr := flagSet.String("addr", "localhost:8080", "address to listen on")
+	workers := flagSet.Int("workers", 1, "number of jobs running at the same time")
+	queue := flagSet.Int("queue", 16, "number of jobs waiting to run, beyond which submissions are refused")
+	timeout := flagSet.Duration("timeout", time.Minute, "timeout of jobs not setting their own")
+	maxTimeout := flagSet.Duration("maxtimeout", 10*time.Minute, "longest timeout a job may set")
+	maxBytes := flagSet.Int64("maxbytes", 64<<20, "largest request accepted, in bytes")
+
+	flagSet.Usage = func() {
+		fmt.Fprintln(flagSet.Output(), "Usage of log-k-decomp serve: [flags]")
+		flagSet.PrintDefaults()
+	}
+	if code, stop := parseFlags(flagSet, args); stop {
+		return code
+	}
+
+	s := service.New(service.Config{Workers: *workers, QueueSize: *queue, Timeout: *timeout,
+		MaxTimeout: *maxTimeout, MaxBytes: *maxBytes})
+	defer s.Close()
+
+	fmt.Fprintln(os.Stderr, "Listening on", *addr)
+	if err := http.ListenAndServe(*addr, s); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	return exitOK
+}
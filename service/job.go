@@ -0,0 +1,330 @@
+package service
+
+// job.go runs a single decomposition request, keeping track of its state so it can be reported while running
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	algo "github.com/cem-okulmus/BalancedGo/algorithms"
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+// The states of a job. Once a job reaches any state other than Queued or Running, it is finished.
+const (
+	Queued    = "queued"
+	Running   = "running"
+	Found     = "found"     // an HD was found
+	NotFound  = "not_found" // no HD of the given width exists
+	Timeout   = "timeout"
+	Cancelled = "cancelled"
+	Failed    = "failed" // the request couldn't be processed
+)
+
+// Algorithms lists the algorithms a request can choose from, the first one being the default
+var Algorithms = []string{"hybrid", "logk", "detk", "detk-subedge"}
+
+// Formats lists the input formats a request can choose from, the first one being the default
+var Formats = logk.FormatNames()
+
+// Request asks for a decomposition of a hypergraph
+type Request struct {
+	Graph      string `json:"graph"`                // the hypergraph, in the chosen format
+	Format     string `json:"format,omitempty"`     // one of Formats
+	Width      int    `json:"width,omitempty"`      // the width to search for, unless Exact is set
+	Exact      bool   `json:"exact,omitempty"`      // search for the smallest width instead
+	Algorithm  string `json:"algorithm,omitempty"`  // one of Algorithms
+	Parallel   bool   `json:"parallel,omitempty"`   // let DetKDecomp explore covers and components concurrently
+	BalFactor  int    `json:"balfactor,omitempty"`  // defaults to 2
+	Preprocess string `json:"preprocess,omitempty"` // comma-separated list of preprocessing steps, without hinge
+	TimeoutMS  int    `json:"timeout_ms,omitempty"` // defaults to the timeout of the service
+}
+
+// Node is a node of a decomposition, using the names of the input
+type Node struct {
+	Bag      []string `json:"bag"`
+	Cover    []string `json:"cover"`
+	Children []Node   `json:"children,omitempty"`
+}
+
+// Result is the outcome of a finished search
+type Result struct {
+	Width   int   `json:"width"`          // the width searched for, or the smallest width in exact mode
+	Correct bool  `json:"correct"`        // whether the HD was checked to be correct
+	Root    *Node `json:"root,omitempty"` // the HD, if found
+}
+
+// Progress reports how far a search got
+type Progress struct {
+	Width     int   `json:"width,omitempty"` // the width currently searched for
+	ElapsedMS int64 `json:"elapsed_ms"`      // time spent searching so far
+}
+
+// Status reports the state of a job
+type Status struct {
+	ID       string   `json:"id"`
+	State    string   `json:"state"`
+	Progress Progress `json:"progress"`
+	Result   *Result  `json:"result,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// job is a request together with its state
+type job struct {
+	id      string
+	request Request
+	timeout time.Duration
+	stop    *logk.StopFlag
+
+	mux      sync.Mutex
+	state    string
+	width    int
+	started  time.Time
+	finished time.Time
+	timedOut bool
+	result   *Result
+	err      error
+}
+
+// status reports the state of the job
+func (j *job) status() Status {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	out := Status{ID: j.id, State: j.state, Progress: Progress{Width: j.width}, Result: j.result}
+	switch {
+	case !j.finished.IsZero() && !j.started.IsZero():
+		out.Progress.ElapsedMS = j.finished.Sub(j.started).Milliseconds()
+	case !j.started.IsZero():
+		out.Progress.ElapsedMS = time.Since(j.started).Milliseconds()
+	}
+	if j.err != nil {
+		out.Error = j.err.Error()
+	}
+
+	return out
+}
+
+// done checks if the job is finished
+func (j *job) done() bool {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	return j.state != Queued && j.state != Running
+}
+
+// cancel stops the job, if it isn't finished yet. A queued job is finished right away.
+func (j *job) cancel() {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	if j.state == Queued {
+		j.state = Cancelled
+		j.finished = time.Now()
+	}
+	j.stop.Stop()
+}
+
+// finish records the outcome of the job
+func (j *job) finish(state string, result *Result, err error) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	j.state = state
+	j.result = result
+	j.err = err
+	j.finished = time.Now()
+}
+
+// validate checks the options of a request, filling in the defaults
+func validate(r *Request) error {
+	if r.Format == "" {
+		r.Format = Formats[0]
+	}
+	if r.Algorithm == "" {
+		r.Algorithm = Algorithms[0]
+	}
+	if r.BalFactor == 0 {
+		r.BalFactor = 2
+	}
+
+	switch {
+	case !contains(Formats, r.Format):
+		return fmt.Errorf("unknown format %q, expected one of %v", r.Format, strings.Join(Formats, ", "))
+	case !contains(Algorithms, r.Algorithm):
+		return fmt.Errorf("unknown algorithm %q, expected one of %v", r.Algorithm, strings.Join(Algorithms, ", "))
+	case !r.Exact && r.Width <= 0:
+		return errors.New("width needs to be positive, unless exact is set")
+	case r.BalFactor < 2:
+		return errors.New("balfactor needs to be at least 2")
+	case r.TimeoutMS < 0:
+		return errors.New("timeout_ms can't be negative")
+	}
+
+	pipeline, err := logk.ParsePipeline(r.Preprocess)
+	if err != nil {
+		return err
+	}
+	if contains(pipeline, "hinge") {
+		return errors.New("the hinge step is not supported by the service")
+	}
+
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for i := range list {
+		if list[i] == s {
+			return true
+		}
+	}
+	return false
+}
+
+// parse reads the hypergraph of the request
+func parse(r Request) (lib.Graph, logk.Encoding, error) {
+	format, err := logk.GetFormat(r.Format)
+	if err != nil {
+		return lib.Graph{}, logk.Encoding{}, err
+	}
+	return format.Read(r.Graph)
+}
+
+// newSolver sets up the algorithm chosen in the request, searching until the stop flag is set
+func newSolver(r Request, graph lib.Graph, stop *logk.StopFlag) algo.Algorithm {
+	var solver algo.Algorithm
+
+	switch r.Algorithm {
+	case "logk":
+		solver = &logk.LogKDecomp{Graph: graph, BalFactor: r.BalFactor, Stop: stop}
+	case "detk", "detk-subedge":
+		solver = &logk.DetKDecomp{Graph: graph, BalFactor: r.BalFactor, SubEdge: r.Algorithm == "detk-subedge",
+			Stop: stop, Parallel: r.Parallel}
+	default:
+		hybrid := &logk.LogKHybrid{Graph: graph, BalFactor: r.BalFactor, Size: 300, Stop: stop, Parallel: r.Parallel}
+		hybrid.Predicate = hybrid.ETimesKDivAvgEdgePred
+		solver = hybrid
+	}
+	solver.SetGenerator(lib.ParallelSearchGen{})
+
+	return solver
+}
+
+// run searches for a decomposition, after preprocessing the hypergraph, reporting the width searched for as
+// progress
+func (j *job) run() {
+	j.mux.Lock()
+	if j.state != Queued { // cancelled while queued
+		j.mux.Unlock()
+		return
+	}
+	j.state = Running
+	j.started = time.Now()
+	j.mux.Unlock()
+
+	// a failing search only fails its job, keeping the worker running
+	defer func() {
+		if r := recover(); r != nil {
+			j.finish(Failed, nil, fmt.Errorf("search failed: %v", r))
+		}
+	}()
+
+	timer := time.AfterFunc(j.timeout, func() {
+		j.mux.Lock()
+		j.timedOut = true
+		j.mux.Unlock()
+		j.stop.Stop()
+	})
+	defer timer.Stop()
+
+	original, encoding, err := parse(j.request)
+	if err != nil {
+		j.finish(Failed, nil, err)
+		return
+	}
+
+	graph := original
+	var reductions []logk.Reduction
+	pipeline, _ := logk.ParsePipeline(j.request.Preprocess)
+	for _, step := range pipeline {
+		var reduction logk.Reduction
+		if graph, reduction, err = logk.Preprocess(step, graph); err != nil {
+			j.finish(Failed, nil, err)
+			return
+		}
+		reductions = append(reductions, reduction)
+	}
+
+	var decomp lib.Decomp
+	width := j.request.Width
+	if graph.Edges.Len() > 0 {
+		solver := newSolver(j.request, graph, j.stop)
+		first, last := width, width
+		if j.request.Exact {
+			first, last = 1, graph.Edges.Len() // a cover of all edges always works
+		}
+
+		for width = first; width <= last && !j.stop.Stopped(); width++ {
+			j.mux.Lock()
+			j.width = width
+			j.mux.Unlock()
+
+			solver.SetWidth(width)
+			if decomp = solver.FindDecomp(); !reflect.DeepEqual(decomp, lib.Decomp{}) {
+				break
+			}
+		}
+	}
+	found := !reflect.DeepEqual(decomp, lib.Decomp{}) || graph.Edges.Len() == 0
+
+	j.mux.Lock()
+	timedOut := j.timedOut
+	j.mux.Unlock()
+
+	switch {
+	case found:
+		for i := len(reductions) - 1; i >= 0; i-- {
+			var ok bool
+			if decomp.Root, ok = reductions[i].Restore(decomp.Root); !ok {
+				j.finish(Failed, nil, errors.New("restoring the preprocessing failed"))
+				return
+			}
+		}
+		decomp.Graph = original
+		decomp.RestoreSubedges()
+
+		root := exportNode(decomp.Root, encoding)
+		if graph.Edges.Len() == 0 {
+			width = decomp.CheckWidth()
+		}
+		j.finish(Found, &Result{Width: width, Correct: decomp.Correct(original), Root: &root}, nil)
+	case timedOut:
+		j.finish(Timeout, nil, nil)
+	case j.stop.Stopped():
+		j.finish(Cancelled, nil, nil)
+	default:
+		j.finish(NotFound, &Result{Width: j.request.Width}, nil)
+	}
+}
+
+// exportNode converts a node of a decomposition, using the names of the input
+func exportNode(n lib.Node, encoding logk.Encoding) Node {
+	out := Node{Bag: []string{}, Cover: []string{}}
+
+	for _, v := range n.Bag {
+		out.Bag = append(out.Bag, encoding.Name(v))
+	}
+	for _, e := range n.Cover.Slice() {
+		out.Cover = append(out.Cover, encoding.Edge(e))
+	}
+	for _, c := range n.Children {
+		out.Children = append(out.Children, exportNode(c, encoding))
+	}
+
+	return out
+}
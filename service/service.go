@@ -0,0 +1,251 @@
+// Package service runs log-k-decomp as a long-lived HTTP service. Hypergraphs are submitted as jobs, which are
+// queued and decomposed by a fixed number of workers, and whose state and result can be queried as JSON.
+//
+// The endpoints are:
+//
+//	POST   /jobs       submit a Request, returning the Status of the new job
+//	GET    /jobs       list the Status of all jobs
+//	GET    /jobs/<id>  the Status of a job, including its result once finished
+//	DELETE /jobs/<id>  cancel a job, or forget it once finished
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+// Config sets the limits of a service
+type Config struct {
+	Workers    int           // number of jobs running at the same time, defaults to 1
+	QueueSize  int           // number of jobs waiting to run, beyond which submissions are refused
+	Timeout    time.Duration // for jobs not setting their own, defaults to one minute
+	MaxTimeout time.Duration // if positive, the longest timeout a job may set
+	MaxBytes   int64         // if positive, the largest request accepted, in bytes
+}
+
+// Server is an http.Handler accepting decomposition jobs
+type Server struct {
+	config Config
+	queue  chan *job
+	wg     sync.WaitGroup
+
+	mux    sync.Mutex
+	jobs   map[string]*job
+	nextID int
+	closed bool
+}
+
+// New starts the workers of a service with the given limits. The service needs to be closed after use.
+func New(config Config) *Server {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = time.Minute
+	}
+
+	s := &Server{config: config, queue: make(chan *job, config.QueueSize), jobs: make(map[string]*job)}
+	for i := 0; i < config.Workers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			for j := range s.queue {
+				j.run()
+			}
+		}()
+	}
+
+	return s
+}
+
+// Close refuses any further jobs, cancels all jobs not yet finished and waits for the workers to stop
+func (s *Server) Close() {
+	s.mux.Lock()
+	if s.closed {
+		s.mux.Unlock()
+		return
+	}
+	s.closed = true
+	for _, j := range s.jobs {
+		j.cancel()
+	}
+	close(s.queue)
+	s.mux.Unlock()
+
+	s.wg.Wait()
+}
+
+// Submit validates the request and queues it as a new job, returning its status. An error is returned if the
+// request is invalid, or the queue is full.
+func (s *Server) Submit(r Request) (Status, error) {
+	if err := validate(&r); err != nil {
+		return Status{}, err
+	}
+
+	timeout := s.config.Timeout
+	if r.TimeoutMS > 0 {
+		timeout = time.Duration(r.TimeoutMS) * time.Millisecond
+	}
+	if s.config.MaxTimeout > 0 && timeout > s.config.MaxTimeout {
+		timeout = s.config.MaxTimeout
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.closed {
+		return Status{}, errClosed
+	}
+	s.nextID++
+	j := &job{id: strconv.Itoa(s.nextID), request: r, timeout: timeout, stop: &logk.StopFlag{}, state: Queued}
+
+	select {
+	case s.queue <- j:
+	default:
+		return Status{}, errQueueFull
+	}
+	s.jobs[j.id] = j
+
+	return j.status(), nil
+}
+
+var (
+	errQueueFull = errors.New("the queue of jobs is full")
+	errClosed    = errors.New("the service is shutting down")
+	errNotFound  = errors.New("no job with this id")
+)
+
+// Status reports the state of a job
+func (s *Server) Status(id string) (Status, error) {
+	s.mux.Lock()
+	j, ok := s.jobs[id]
+	s.mux.Unlock()
+
+	if !ok {
+		return Status{}, errNotFound
+	}
+	return j.status(), nil
+}
+
+// Cancel stops a job not finished yet, or forgets a finished one, returning its last status
+func (s *Server) Cancel(id string) (Status, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	j, ok := s.jobs[id]
+	if !ok {
+		return Status{}, errNotFound
+	}
+	if j.done() {
+		delete(s.jobs, id)
+	} else {
+		j.cancel()
+	}
+
+	return j.status(), nil
+}
+
+// List reports the state of all jobs, in the order they were submitted
+func (s *Server) List() []Status {
+	s.mux.Lock()
+	var jobs []*job
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	s.mux.Unlock()
+
+	output := []Status{}
+	for _, j := range jobs {
+		output = append(output, j.status())
+	}
+	sort.Slice(output, func(i, k int) bool {
+		a, _ := strconv.Atoi(output[i].ID)
+		b, _ := strconv.Atoi(output[k].ID)
+		return a < b
+	})
+
+	return output
+}
+
+// errorBody is sent in place of a status if a request fails
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := http.StatusBadRequest
+	switch err {
+	case errQueueFull, errClosed:
+		code = http.StatusServiceUnavailable
+	case errNotFound:
+		code = http.StatusNotFound
+	}
+
+	writeJSON(w, code, errorBody{Error: err.Error()})
+}
+
+// ServeHTTP implements the endpoints of the service
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	if path != "jobs" && !strings.HasPrefix(path, "jobs/") {
+		writeJSON(w, http.StatusNotFound, errorBody{Error: "unknown endpoint"})
+		return
+	}
+	id := strings.TrimPrefix(strings.TrimPrefix(path, "jobs"), "/")
+
+	switch {
+	case id == "" && r.Method == http.MethodPost:
+		body := r.Body
+		if s.config.MaxBytes > 0 {
+			body = http.MaxBytesReader(w, r.Body, s.config.MaxBytes)
+		}
+
+		var request Request
+		if err := json.NewDecoder(body).Decode(&request); err != nil {
+			writeError(w, err)
+			return
+		}
+		status, err := s.Submit(request)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, status)
+
+	case id == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s.List())
+
+	case id != "" && r.Method == http.MethodGet:
+		status, err := s.Status(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+
+	case id != "" && r.Method == http.MethodDelete:
+		status, err := s.Cancel(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, errorBody{Error: "method not allowed"})
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+// The stats command, reporting structural properties of hypergraphs before choosing settings to decompose them
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+// statsFiles collects the files at the given paths, descending into directories to cover a whole corpus
+func statsFiles(paths []string) ([]string, error) {
+	var output []string
+
+	for _, path := range paths {
+		err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
+				output = append(output, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return output, nil
+}
+
+// runStats implements the stats command, with the arguments following it
+func runStats(args []string) int {
+	flagSet := flag.NewFlagSet("stats", flag.ContinueOnError)
+	graphPath := flagSet.String("graph", "", "input file or directory, as for the other commands; further ones can be given as arguments")
+	jsonOut := flagSet.Bool("json", false, "output the statistics as a JSON array")
+	pace := flagSet.Bool("pace", false, "Use PACE 2019 format for graphs (see pacechallenge.org/2019/htd/htd_format/)")
+	gr := flagSet.Bool("gr", false, "Use PACE 2017 format for graphs (see pacechallenge.org/2017/treewidth/)")
+	vcLimit := flagSet.Int("vclimit", logk.VCLimit, "number of vertex sets checked when computing the VC dimension")
+
+	flagSet.Usage = func() {
+		fmt.Fprintln(flagSet.Output(), "Usage of log-k-decomp stats: [flags] [<file or directory> ...]")
+		flagSet.PrintDefaults()
+	}
+	if code, stop := parseFlags(flagSet, args); stop {
+		return code
+	}
+	paths := flagSet.Args()
+	if len(*graphPath) > 0 {
+		paths = append([]string{*graphPath}, paths...)
+	}
+	if len(paths) == 0 {
+		flagSet.Usage()
+		return exitError
+	}
+	logk.VCLimit = *vcLimit
+
+	files, err := statsFiles(paths)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+
+	stats := []logk.Stats{}
+	for _, file := range files {
+		dat, err := logk.ReadInput(file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Skipping", file+":", err)
+			continue
+		}
+
+		documents := logk.SplitDocuments(string(dat), *pace || *gr)
+		for i := range documents {
+			name := file
+			if len(documents) > 1 {
+				name = fmt.Sprintf("%s (document %d of %d)", file, i+1, len(documents))
+			}
+
+			var graph lib.Graph
+			switch {
+			case *gr:
+				graph, _, err = logk.GetGraphGR(documents[i])
+			case *pace:
+				graph, _, err = logk.GetGraphPACE(documents[i])
+			default:
+				graph, _, err = logk.GetGraph(documents[i])
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "Skipping", name+":", err)
+				continue
+			}
+
+			s := logk.GetStats(graph, name)
+			if *jsonOut {
+				stats = append(stats, s)
+			} else {
+				fmt.Println(s)
+			}
+		}
+	}
+
+	if *jsonOut {
+		out, err := json.MarshalIndent(stats, "", "  ")
+		check(err)
+		fmt.Println(string(out))
+	}
+
+	return exitOK
+}
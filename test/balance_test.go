@@ -0,0 +1,87 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	"github.com/cem-okulmus/disjoint"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+//TestParseBalance checks that ratios and integer factors are read, and that ratios not in [1/2, 1) are rejected
+func TestParseBalance(t *testing.T) {
+	valid := map[string]logk.Balance{
+		"3/5": {Num: 3, Den: 5},
+		"1/2": {Num: 1, Den: 2},
+		"2":   {Num: 1, Den: 2},
+		"3":   {Num: 2, Den: 3},
+	}
+	for ratio, expected := range valid {
+		b, err := logk.ParseBalance(ratio)
+		if err != nil || b.Num != expected.Num || b.Den != expected.Den {
+			t.Errorf("ParseBalance(%q) = %v, %v, expected %v", ratio, b, err, expected)
+		}
+	}
+
+	for _, ratio := range []string{"1/3", "1/1", "5/4", "0/0", "1", "a/b", ""} {
+		if _, err := logk.ParseBalance(ratio); err == nil {
+			t.Errorf("ParseBalance(%q) accepted", ratio)
+		}
+	}
+}
+
+//TestBalancedCheck ensures that the balance of an integer factor accepts the same separators as BalancedGo
+func TestBalancedCheck(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		graph, _ := getRandomGraph(20)
+		sep := getRandomSep(graph, 4)
+
+		for f := 2; f <= 4; f++ {
+			expected := lib.BalancedCheck{}.Check(&graph, &sep, f, make(map[int]*disjoint.Element))
+			unset := logk.BalancedCheck{}.Check(&graph, &sep, f, make(map[int]*disjoint.Element))
+			set := logk.BalancedCheck{Balance: logk.NewBalance(f)}.Check(&graph, &sep, 2,
+				make(map[int]*disjoint.Element))
+
+			if unset != expected || set != expected {
+				t.Errorf("factor %d, graph %v, sep %v: expected %v, got %v and %v", f, graph, sep, expected,
+					unset, set)
+			}
+		}
+	}
+}
+
+//TestBalanceMeasures checks the measures on a path a - b - c - d - e, separated at c
+func TestBalanceMeasures(t *testing.T) {
+	e1 := lib.Edge{Name: 1, Vertices: []int{11, 12}}
+	e2 := lib.Edge{Name: 2, Vertices: []int{12, 13}}
+	e3 := lib.Edge{Name: 3, Vertices: []int{13, 14}}
+	e4 := lib.Edge{Name: 4, Vertices: []int{14, 15}}
+	e5 := lib.Edge{Name: 5, Vertices: []int{15, 16}}
+	H := lib.Graph{Edges: lib.NewEdges([]lib.Edge{e1, e2, e3, e4, e5})}
+	sep := lib.NewEdges([]lib.Edge{e2})
+	left := lib.Graph{Edges: lib.NewEdges([]lib.Edge{e1})}
+	right := lib.Graph{Edges: lib.NewEdges([]lib.Edge{e3, e4, e5})}
+
+	half := logk.Balance{Num: 1, Den: 2}
+	if half.TooLarge(H, left, sep) || !half.TooLarge(H, right, sep) {
+		t.Error("edges: expected only the right component to be too large for 1/2")
+	}
+	if (logk.Balance{Num: 3, Den: 5}).TooLarge(H, right, sep) {
+		t.Error("edges: the right component has 3 of 5 edges, allowed by 3/5")
+	}
+
+	// the right component has the vertices 14, 15, 16 outside the separator, of 6 in total
+	vertices := logk.Balance{Num: 1, Den: 2, Measure: logk.VertexMeasure}
+	if vertices.TooLarge(H, right, sep) {
+		t.Error("vertices: the right component has 3 of 6 vertices, allowed by 1/2")
+	}
+
+	weights := logk.Balance{Num: 1, Den: 2, Measure: logk.WeightMeasure, Weights: logk.Weights{1: 100}}
+	if !weights.TooLarge(H, left, sep) || weights.TooLarge(H, right, sep) {
+		t.Error("weights: expected only the left component, weighing 100 of 104, to be too large")
+	}
+
+	if !half.TooLarge(H, H, lib.NewEdges([]lib.Edge{})) {
+		t.Error("a component as large as the hypergraph should always be too large")
+	}
+}
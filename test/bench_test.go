@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	algo "github.com/cem-okulmus/BalancedGo/algorithms"
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+//go:embed testdata/*.hg
+var corpus embed.FS
+
+// corpusWidths gives for each instance of the corpus its hypertree width, the benchmarks are run for the width
+// itself, finding an HD, and one below, showing that none exists
+var corpusWidths = map[string]int{
+	"acyclic": 1,
+	"cycle":   2,
+	"grid":    3,
+	"clique":  3,
+	"csp":     3,
+}
+
+// benchSolver creates a fresh instance of an algorithm for each run
+type benchSolver struct {
+	name string
+	new  func() algo.Algorithm
+}
+
+// benchSolvers lists the algorithms compared by the benchmarks: LogKDecomp, LogKHybrid with each of its predicates
+// and DetKDecomp, sequential and parallel
+func benchSolvers() []benchSolver {
+	hybrid := func(i int) func() algo.Algorithm {
+		return func() algo.Algorithm { return newHybrid(i) }
+	}
+
+	return []benchSolver{
+		{"logk", func() algo.Algorithm { return &logk.LogKDecomp{BalFactor: 2} }},
+		{"hybrid1", hybrid(0)},
+		{"hybrid2", hybrid(1)},
+		{"hybrid3", hybrid(2)},
+		{"hybrid4", hybrid(3)},
+		{"detk", func() algo.Algorithm { return &logk.DetKDecomp{BalFactor: 2} }},
+		{"detk-parallel", func() algo.Algorithm { return &logk.DetKDecomp{BalFactor: 2, Parallel: true} }},
+	}
+}
+
+// loadCorpus reads an instance of the corpus
+func loadCorpus(b *testing.B, name string) lib.Graph {
+	dat, err := corpus.ReadFile(path.Join("testdata", name+".hg"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	graph, _, err := logk.GetGraph(string(dat))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return graph
+}
+
+//BenchmarkCorpus runs each algorithm on each instance of the corpus, at its width and one below, reporting besides
+// time and allocations the goroutines created and the use of the cache per search
+func BenchmarkCorpus(b *testing.B) {
+	entries, err := corpus.ReadDir("testdata")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".hg")
+		width, ok := corpusWidths[name]
+		if !ok {
+			b.Fatalf("no width given for instance %v", name)
+		}
+		graph := loadCorpus(b, name)
+
+		for k := width - 1; k <= width; k++ {
+			if k < 1 {
+				continue
+			}
+
+			for _, solver := range benchSolvers() {
+				b.Run(fmt.Sprintf("%s/k=%d/%s", name, k, solver.name), func(b *testing.B) {
+					benchSearch(b, solver, graph, k, k == width)
+				})
+			}
+		}
+	}
+}
+
+// benchSearch runs the search b.N times, each with a fresh instance of the algorithm
+func benchSearch(b *testing.B, solver benchSolver, graph lib.Graph, k int, exists bool) {
+	type counted interface {
+		CacheStats() logk.CacheStats
+		SearchStats() logk.SearchStats
+	}
+	var stats logk.CacheStats
+	var search logk.SearchStats
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s := solver.new()
+		s.SetGenerator(lib.ParallelSearchGen{})
+		s.SetWidth(k)
+
+		decomp := s.FindDecompGraph(lib.Graph{Edges: graph.Edges})
+		if found := !reflect.DeepEqual(decomp, lib.Decomp{}); found != exists {
+			b.Fatalf("expected HD of width %d to exist: %v, found: %v", k, exists, found)
+		}
+
+		c, ok := s.(counted)
+		if !ok {
+			b.Fatalf("%s doesn't count its work", solver.name)
+		}
+		run := c.CacheStats()
+		stats.Checks += run.Checks
+		stats.Hits += run.Hits
+		stats.Added += run.Added
+		stats.Entries += run.Entries
+		search.Goroutines += c.SearchStats().Goroutines
+		search.DetKCalls += c.SearchStats().DetKCalls
+	}
+
+	b.StopTimer()
+	n := float64(b.N)
+	b.ReportMetric(float64(search.Goroutines)/n, "goroutines/op")
+	b.ReportMetric(float64(search.DetKCalls)/n, "detk-calls/op")
+	b.ReportMetric(float64(stats.Checks)/n, "cache-checks/op")
+	b.ReportMetric(float64(stats.Hits)/n, "cache-hits/op")
+	b.ReportMetric(float64(stats.Added)/n, "cache-added/op")
+	b.ReportMetric(float64(stats.Entries)/n, "cache-entries/op")
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+//TestComponents checks splitting a hypergraph into its connected components, and joining decompositions of them
+func TestComponents(t *testing.T) {
+	graph, encoding, err := logk.GetGraph("R(a,b), A(x,y), S(b,c), B(y,z), T(c,a), F(p,q).")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comps := logk.ConnectedComponents(graph)
+	if len(comps) != 3 || comps[0].Edges.Len() != 3 || comps[1].Edges.Len() != 2 || comps[2].Edges.Len() != 1 {
+		t.Fatalf("wrong components: %v", comps)
+	}
+
+	var decomps []lib.Decomp
+	for _, c := range comps {
+		decomps = append(decomps, lib.Decomp{Graph: c, Root: lib.Node{Bag: c.Vertices(), Cover: c.Edges}})
+	}
+
+	joined := logk.JoinDecomps(graph, decomps)
+	if !joined.Correct(graph) || joined.CheckWidth() != 3 || len(joined.Root.Children) != 2 {
+		t.Errorf("wrong joined decomposition:\n%v", encoding.Decomp(joined))
+	}
+}
+
+//TestComponentsEmptyEdge checks that edges without vertices neither crash the split into components nor the stats
+func TestComponentsEmptyEdge(t *testing.T) {
+	graph, _, err := logk.GetGraph("R(), S(a,b), T(c).")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comps := logk.ConnectedComponents(graph)
+	if len(comps) != 2 || comps[0].Edges.Len() != 2 || comps[1].Edges.Len() != 1 {
+		t.Fatalf("wrong components: %v", comps)
+	}
+
+	if s := logk.GetStats(graph, "empty"); s.Components != 2 {
+		t.Errorf("expected 2 components, got %d", s.Components)
+	}
+}
@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+//TestCost checks reading weights, the cost estimates of a decomposition and the rejection of expensive separators
+func TestCost(t *testing.T) {
+	graph, encoding, decomp, db := triangleQuery(t)
+
+	weights, err := logk.ReadWeights(strings.NewReader("% cardinalities\nR 4\nS 3\n\nU 2.5\n"), graph, encoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := logk.ReadWeights(strings.NewReader("X 4\n"), graph, encoding); err == nil {
+		t.Error("weight of unknown edge not rejected")
+	}
+	if _, err := logk.ReadWeights(strings.NewReader("x 4\n"), graph, encoding); err == nil {
+		t.Error("weight of vertex not rejected")
+	}
+
+	total, max := weights.Annotate(&decomp)
+	if decomp.Root.Cost != 12 || decomp.Root.Children[0].Cost != 2.5 || total != 14.5 || max != 12 {
+		t.Errorf("wrong costs: root %v, child %v, total %v, max %v", decomp.Root.Cost,
+			decomp.Root.Children[0].Cost, total, max)
+	}
+
+	sorted := weights.Sort(graph.Edges)
+	var names []string
+	for _, e := range sorted.Slice() {
+		names = append(names, encoding.Name(e.Name))
+	}
+	if strings.Join(names, " ") != "T V U S R" {
+		t.Errorf("wrong order of edges: %v", names)
+	}
+	if encoding.Name(graph.Edges.Slice()[0].Name) != "R" {
+		t.Error("sorting modified the input")
+	}
+
+	if sizes := logk.WeightsFromDatabase(db); sizes.Cost(decomp.Root.Cover) != 12 {
+		t.Errorf("wrong cost from relation sizes: %v", sizes.Cost(decomp.Root.Cover))
+	}
+
+	pred := weights.WithCost(lib.BalancedCheck{}, 10)
+	if pred.Check(&graph, &decomp.Root.Cover, 2, nil) {
+		t.Error("separator exceeding the cost bound accepted")
+	}
+}
@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+//TestDetKPar compares parallel and sequential DetKDecomp on the corpus and on random hypergraphs, checking that
+// they agree on whether an HD exists, and that the parallel search produces correct HDs using goroutines of its pool
+func TestDetKPar(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4)) // so that the pool has goroutines to offer
+
+	var graphs []lib.Graph
+	for name := range corpusWidths {
+		dat, err := corpus.ReadFile("testdata/" + name + ".hg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		graph, _, err := logk.GetGraph(string(dat))
+		if err != nil {
+			t.Fatal(err)
+		}
+		graphs = append(graphs, graph)
+	}
+	for i := 0; i < 5; i++ {
+		randGraph, _ := getRandomGraph(12)
+		graphs = append(graphs, randGraph)
+	}
+
+	for _, graph := range graphs {
+		for k := 1; k <= 3; k++ {
+			seq := &logk.DetKDecomp{Graph: graph, BalFactor: 2}
+			seq.SetWidth(k)
+			par := &logk.DetKDecomp{Graph: graph, BalFactor: 2, Parallel: true}
+			par.SetWidth(k)
+
+			decompSeq := seq.FindDecomp()
+			decompPar := par.FindDecomp()
+
+			foundSeq := !reflect.DeepEqual(decompSeq, lib.Decomp{})
+			foundPar := !reflect.DeepEqual(decompPar, lib.Decomp{})
+			if foundSeq != foundPar {
+				t.Errorf("width %d: sequential found HD %v, parallel %v, for %v", k, foundSeq, foundPar, graph)
+				continue
+			}
+			if foundPar && (!decompPar.Correct(graph) || decompPar.CheckWidth() > k) {
+				t.Errorf("width %d: parallel HD not correct:\n%v", k, decompPar)
+			}
+			if graph.Edges.Len() > k && par.SearchStats().Goroutines == 0 {
+				t.Errorf("width %d: no goroutines used by the parallel search for %v", k, graph)
+			}
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package tests
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	algo "github.com/cem-okulmus/BalancedGo/algorithms"
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+// counterexamples is the directory where mismatching instances are saved
+const counterexamples = "counterexamples"
+
+// seed of the random hypergraphs of TestDifferential, fixed so that reported counterexamples can be reproduced
+var seed = flag.Int64("seed", 1, "seed of the random hypergraphs of TestDifferential")
+
+// hybridSizes gives for each predicate of LogKHybrid, in the order of newHybrid, a threshold at which it switches
+// to DetKDecomp below the root within the random instances and the acyclic, cycle and grid instances of the corpus.
+// The clique and csp instances leave only base cases below the root, so they switch at the root, if at all.
+var hybridSizes = []int{6, 20, 8, 0}
+
+// newHybrid creates a LogKHybrid using the i-th predicate, with its threshold from hybridSizes
+func newHybrid(i int) *logk.LogKHybrid {
+	l := &logk.LogKHybrid{BalFactor: 2, Size: hybridSizes[i]}
+	l.Predicate = []logk.HybridPredicate{l.NumberEdgesPred, l.SumEdgesPred, l.ETimesKDivAvgEdgePred,
+		l.OneRoundPred}[i]
+	return l
+}
+
+// differentialSolvers returns the algorithms compared by the differential tests: LogKDecomp, LogKHybrid with each
+// of its predicates and DetKDecomp, sequential and parallel
+func differentialSolvers() []algo.Algorithm {
+	var output []algo.Algorithm
+
+	logK := &logk.LogKDecomp{BalFactor: 2}
+	output = append(output, logK)
+
+	for i := range hybridSizes {
+		output = append(output, newHybrid(i))
+	}
+
+	output = append(output, &logk.DetKDecomp{BalFactor: 2}, &logk.DetKDecomp{BalFactor: 2, Parallel: true})
+
+	for i := range output {
+		output[i].SetGenerator(lib.ParallelSearchGen{})
+	}
+
+	return output
+}
+
+// disagreement runs the algorithms on the graph, and describes why they disagree, or returns the empty string if
+// they agree on whether an HD of width k exists, and all produced HDs are correct
+func disagreement(solvers []algo.Algorithm, graph lib.Graph, k int) string {
+	var found, notFound []string
+
+	for i, solver := range solvers {
+		name := fmt.Sprintf("%s (#%d)", solver.Name(), i)
+		solver.SetWidth(k)
+		decomp := solver.FindDecompGraph(lib.Graph{Edges: graph.Edges})
+
+		if reflect.DeepEqual(decomp, lib.Decomp{}) {
+			notFound = append(notFound, name)
+			continue
+		}
+		if !decomp.Correct(graph) {
+			return fmt.Sprintf("%s produced an incorrect HD:\n%v", name, decomp)
+		}
+		if decomp.CheckWidth() > k {
+			return fmt.Sprintf("%s produced an HD of width %d > %d:\n%v", name, decomp.CheckWidth(), k, decomp)
+		}
+		found = append(found, name)
+	}
+
+	if len(found) > 0 && len(notFound) > 0 {
+		return fmt.Sprintf("found by %s, but not by %s", strings.Join(found, ", "), strings.Join(notFound, ", "))
+	}
+
+	return ""
+}
+
+// shrink greedily removes edges, and then vertices of edges, from the graph as long as the algorithms still
+// disagree, returning a minimal counterexample
+func shrink(graph lib.Graph, k int) lib.Graph {
+	return shrinkBy(graph, func(g lib.Graph) bool { return disagreement(differentialSolvers(), g, k) != "" })
+}
+
+// shrinkBy greedily removes edges, and then vertices of edges, from the graph as long as it fails
+func shrinkBy(graph lib.Graph, fails func(lib.Graph) bool) lib.Graph {
+	for changed := true; changed; {
+		changed = false
+		edges := graph.Edges.Slice()
+
+		var candidates []lib.Graph
+		for i := range edges {
+			if len(edges) > 1 {
+				smaller := append(append([]lib.Edge{}, edges[:i]...), edges[i+1:]...)
+				candidates = append(candidates, lib.Graph{Edges: lib.NewEdges(smaller)})
+			}
+		}
+		for i := range edges {
+			for j := range edges[i].Vertices {
+				if len(edges[i].Vertices) > 1 {
+					smaller := append([]lib.Edge{}, edges...)
+					vertices := append(append([]int{}, edges[i].Vertices[:j]...), edges[i].Vertices[j+1:]...)
+					smaller[i] = lib.Edge{Name: edges[i].Name, Vertices: vertices}
+					candidates = append(candidates, lib.Graph{Edges: lib.NewEdges(smaller)})
+				}
+			}
+		}
+
+		for _, candidate := range candidates {
+			if fails(candidate) {
+				graph = candidate
+				changed = true
+				break
+			}
+		}
+	}
+
+	return graph
+}
+
+// saveCounterexample writes the graph in HyperBench format, returning the path of the file
+func saveCounterexample(graph lib.Graph, k int) (string, error) {
+	if err := os.MkdirAll(counterexamples, 0755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(counterexamples, fmt.Sprintf("width%d-%d.hg", k, graph.Edges.Hash()))
+	return path, ioutil.WriteFile(path, []byte(graph.ToHyberBenchFormat()+"\n"), 0644)
+}
+
+//TestDifferential checks on random hypergraphs that all algorithms agree on whether an HD of width k exists, and
+// that each HD they produce is correct. Mismatching instances are shrunk and saved as HyperBench files, and the
+// seed producing them is reported, which can be set via -seed to reproduce them.
+func TestDifferential(t *testing.T) {
+	runs := 40
+	if testing.Short() {
+		runs = 10
+	}
+	r := rand.New(rand.NewSource(*seed))
+	solvers := differentialSolvers()
+
+	for i := 0; i < runs; i++ {
+		generated, err := logk.RandomHypergraph(r, r.Intn(12)+1, r.Intn(12)+1, 6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		graph, _, err := generated.Graph()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for k := 1; k <= 3; k++ {
+			reason := disagreement(solvers, graph, k)
+			if reason == "" {
+				continue
+			}
+
+			minimal := shrink(graph, k)
+			path, err := saveCounterexample(minimal, k)
+			if err != nil {
+				t.Error("couldn't save counterexample:", err)
+			}
+			t.Errorf("-seed %d, run %d, width %d: %s\nminimal counterexample, saved to %s: %v\nreason: %s", *seed, i,
+				k, reason, path, minimal, disagreement(differentialSolvers(), minimal, k))
+		}
+	}
+
+	for i, solver := range solvers {
+		if hybrid, ok := solver.(*logk.LogKHybrid); ok && hybrid.SearchStats().DetKCalls == 0 {
+			t.Errorf("-seed %d: LogKHybrid (#%d) never switched to DetKDecomp", *seed, i)
+		}
+	}
+}
+
+//TestShrink checks that shrinking keeps an instance failing, and removes everything not needed for the failure
+func TestShrink(t *testing.T) {
+	graph := lib.Graph{Edges: lib.NewEdges([]lib.Edge{
+		{Name: 1, Vertices: []int{11, 12, 13}},
+		{Name: 2, Vertices: []int{13, 14}},
+		{Name: 3, Vertices: []int{14, 15, 11}},
+	})}
+
+	minimal := shrinkBy(graph, func(lib.Graph) bool { return true })
+	if minimal.Edges.Len() != 1 || len(minimal.Edges.Slice()[0].Vertices) != 1 {
+		t.Errorf("expected a single edge with a single vertex, got %v", minimal)
+	}
+
+	// the graph fails as long as it contains an edge with at least two vertices
+	minimal = shrinkBy(graph, func(g lib.Graph) bool {
+		for _, e := range g.Edges.Slice() {
+			if len(e.Vertices) > 1 {
+				return true
+			}
+		}
+		return false
+	})
+	if minimal.Edges.Len() != 1 || len(minimal.Edges.Slice()[0].Vertices) != 2 {
+		t.Errorf("expected a single edge with two vertices, got %v", minimal)
+	}
+}
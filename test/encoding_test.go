@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+//TestEncodingNames ensures that printing a decomposition uses the names of the input, both for the HyperBench and
+// the PACE format, and that these survive the restoration of a type collapse
+func TestEncodingNames(t *testing.T) {
+	graph, encoding, err := logk.GetGraph("R(x,y,u), S(y,z,u), T(z,x).")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collapsed, removalMap, count := graph.TypeCollapse()
+	if count != 1 {
+		t.Fatalf("expected one collapsed vertex, got %v", count)
+	}
+
+	root := lib.Node{Bag: collapsed.Vertices(), Cover: lib.NewEdges(collapsed.Edges.Slice()[:2])}
+	root, ok := root.RestoreTypes(removalMap)
+	if !ok {
+		t.Fatal("type restoration failed")
+	}
+
+	output := encoding.Decomp(lib.Decomp{Graph: graph, Root: root})
+	for _, name := range []string{"x", "y", "z", "u", "R", "S"} {
+		if !strings.Contains(output, name) {
+			t.Errorf("name %v missing in output %v", name, output)
+		}
+	}
+
+	gml := encoding.GML(lib.Decomp{Graph: graph, Root: root})
+	if !strings.Contains(gml, "{R, S}") {
+		t.Errorf("GML output doesn't use original names: %v", gml)
+	}
+
+	pace, encodingPACE, err := logk.GetGraphPACE("c comment\np htd 3 2\n1 1 2\n2 2 3\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pace.Edges.Len() != 2 {
+		t.Fatalf("expected 2 edges, got %v", pace.Edges.Len())
+	}
+	if out := encodingPACE.Edges(pace.Edges); out != "{E1, E2}" {
+		t.Errorf("unexpected edge names %v", out)
+	}
+	if out := encodingPACE.Vertices(pace.Vertices()); out != "(V1, V2, V3)" {
+		t.Errorf("unexpected vertex names %v", out)
+	}
+
+	if _, _, err := logk.GetGraph("R(x,y"); err == nil {
+		t.Error("malformed input not rejected")
+	}
+}
+
+//TestReadGML checks that decompositions written in GML are read back unchanged, and that malformed trees are
+// rejected
+func TestReadGML(t *testing.T) {
+	graph, encoding, err := logk.GetGraph("R(x,y,u), S(y,z,u), T(z,x), U(x,w), V(w,v,y).")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	solver := &logk.DetKDecomp{BalFactor: 2}
+	solver.SetWidth(2)
+	decomp := solver.FindDecompGraph(graph)
+	if !decomp.Correct(graph) {
+		t.Fatal("no HD found")
+	}
+	decomp.RestoreSubedges()
+
+	gml := encoding.GML(decomp)
+	read, err := logk.ReadGML(strings.NewReader(gml), graph, encoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !read.Correct(graph) || read.CheckWidth() != decomp.CheckWidth() {
+		t.Errorf("decomposition read back is not correct, or of a different width: %v", encoding.Decomp(read))
+	}
+	if out, expected := encoding.Decomp(read), encoding.Decomp(decomp); out != expected {
+		t.Errorf("expected %v, got %v", expected, out)
+	}
+
+	malformed := []string{
+		"graph [\n]\n",
+		"graph [\n  node [\n    id 1\n    label \"{X} {x}\"\n  ]\n]\n",
+		"graph [\n  node [\n    id 1\n    label \"{R} {x, S}\"\n  ]\n]\n",
+		"graph [\n  node [\n    id 1\n    label \"{R} {x, y, u}\"\n  ]\n  node [\n    id 2\n" +
+			"    label \"{S} {y, z, u}\"\n  ]\n]\n",
+		"graph [\n  node [\n    id 1\n    label \"{R} {x, y, u}\"\n  ]\n  edge [\n    source 1\n    target 2\n  ]\n]\n",
+	}
+	for i, input := range malformed {
+		if _, err := logk.ReadGML(strings.NewReader(input), graph, encoding); err == nil {
+			t.Errorf("malformed GML %d not rejected", i)
+		}
+	}
+}
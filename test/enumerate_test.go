@@ -0,0 +1,55 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+//TestEnumerateStop checks that the enumeration of all HDs of a grid, which takes far too long, ends soon after
+// it is stopped, and that a stopped flag prevents any decompositions from being found
+func TestEnumerateStop(t *testing.T) {
+	g, err := logk.Grid(4, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph, _, err := g.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stop := &logk.StopFlag{}
+	logK := logk.LogKDecomp{Graph: graph, K: 3, BalFactor: 2, Stop: stop}
+	logK.SetGenerator(lib.ParallelSearchGen{})
+
+	timer := time.AfterFunc(500*time.Millisecond, stop.Stop)
+	defer timer.Stop()
+
+	done := make(chan int)
+	go func() {
+		found := 0
+		logK.Enumerate(logk.EquivCovers, func(decomp lib.Decomp) bool {
+			found++
+			return true
+		})
+		done <- found
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("enumeration not stopped")
+	}
+
+	logK.SetWidth(3) // empties the cache
+	found := 0
+	logK.Enumerate(logk.EquivCovers, func(decomp lib.Decomp) bool {
+		found++
+		return true
+	})
+	if found > 0 {
+		t.Errorf("%d decompositions enumerated after the search was stopped", found)
+	}
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+//TestEquivalence checks which decompositions are considered the same by each equivalence
+func TestEquivalence(t *testing.T) {
+	graph, _, err := logk.GetGraph("R(a,b), S(b,c), T(c,d), U(d,e).")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := graph.Edges.Slice()
+
+	leafR := lib.Node{Bag: e[0].Vertices, Cover: lib.NewEdges(e[0:1])}
+	leafU := lib.Node{Bag: e[3].Vertices, Cover: lib.NewEdges(e[3:4])}
+	middle := lib.RemoveDuplicates(append(append([]int{}, e[1].Vertices...), e[2].Vertices...))
+
+	decomp := lib.Decomp{Graph: graph, Root: lib.Node{Bag: middle, Cover: lib.NewEdges(e[1:3]),
+		Children: []lib.Node{leafR, leafU}}}
+	swapped := lib.Decomp{Graph: graph, Root: lib.Node{Bag: middle, Cover: lib.NewEdges([]lib.Edge{e[2], e[1]}),
+		Children: []lib.Node{leafU, leafR}}}
+	otherCover := lib.Decomp{Graph: graph, Root: lib.Node{Bag: middle, Cover: lib.NewEdges(e[:3]),
+		Children: []lib.Node{leafR, leafU}}}
+	rerooted := lib.Decomp{Graph: graph, Root: lib.Node{Bag: e[0].Vertices, Cover: lib.NewEdges(e[0:1]),
+		Children: []lib.Node{{Bag: middle, Cover: lib.NewEdges(e[1:3]), Children: []lib.Node{leafU}}}}}
+
+	tests := []struct {
+		decomp lib.Decomp
+		other  lib.Decomp
+		same   []bool // expected for covers, bags and unrooted
+	}{
+		{decomp, swapped, []bool{true, true, true}},
+		{decomp, otherCover, []bool{false, true, true}},
+		{decomp, rerooted, []bool{false, false, true}},
+	}
+
+	for i, test := range tests {
+		for j, equiv := range []logk.Equivalence{logk.EquivCovers, logk.EquivBags, logk.EquivUnrooted} {
+			if same := equiv.Key(test.decomp) == equiv.Key(test.other); same != test.same[j] {
+				t.Errorf("case %d, equivalence %d: got %v, expected %v", i, j, same, test.same[j])
+			}
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package tests
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+// triangleData holds the relations of the triangle query in CSV format
+var triangleData = map[string]string{
+	"R": "1,2\n2,3\n3,1\n1,4\n",
+	"S": "2,3\n3,1\n4,5\n",
+	"T": "3,1\n1,2\n5,1\n",
+	"U": "1,7\n2,8\n1,9\n",
+	"V": "7,7\n8,6\n9,9\n",
+}
+
+// triangleQuery sets up a triangle query with a dangling path, together with a hand-made HD and a database
+func triangleQuery(t *testing.T) (lib.Graph, logk.Encoding, lib.Decomp, logk.Database) {
+	graph, encoding, err := logk.GetGraph("R(x,y), S(y,z), T(z,x), U(x,w), V(w,w).")
+	if err != nil {
+		t.Fatal(err)
+	}
+	edges := graph.Edges.Slice()
+
+	decomp := lib.Decomp{Graph: graph, Root: lib.Node{
+		Bag:   []int{edges[0].Vertices[0], edges[0].Vertices[1], edges[1].Vertices[1]},
+		Cover: lib.NewEdges(edges[:2]),
+		Children: []lib.Node{{
+			Bag:   edges[3].Vertices,
+			Cover: lib.NewEdges(edges[3:4]),
+		}},
+	}}
+	if !decomp.Correct(graph) {
+		t.Fatal("hand-made decomposition not correct")
+	}
+
+	db := make(logk.Database)
+	for _, e := range edges {
+		rel, err := logk.ReadCSV(strings.NewReader(triangleData[encoding.Name(e.Name)]), e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db[e.Name] = rel
+	}
+
+	return graph, encoding, decomp, db
+}
+
+//TestEvaluate checks the evaluation of a query over a decomposition against the expected answers
+func TestEvaluate(t *testing.T) {
+	graph, encoding, decomp, db := triangleQuery(t)
+
+	answers, err := logk.Evaluate(decomp, graph, db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// x=1 with w in {7,9}, for the triangles (1,2,3) and (1,4,5)
+	if answers.Len() != 4 {
+		var sb strings.Builder
+		answers.WriteCSV(&sb, encoding)
+		t.Errorf("expected 4 answers, got %v:\n%v", answers.Len(), sb.String())
+	}
+
+	x, _ := encoding.ID("x")
+	w, _ := encoding.ID("w")
+	projected, err := logk.Evaluate(decomp, graph, db, []int{x, w})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if projected.Len() != 2 {
+		t.Errorf("expected 2 projected answers, got %v", projected.Len())
+	}
+
+	nonEmpty, err := logk.Decide(decomp, graph, db)
+	if err != nil || !nonEmpty {
+		t.Errorf("query wrongly decided to be empty (error %v)", err)
+	}
+
+	u := graph.Edges.Slice()[3]
+	db[u.Name] = logk.Relation{Attributes: db[u.Name].Attributes}
+	nonEmpty, err = logk.Decide(decomp, graph, db)
+	if err != nil || nonEmpty {
+		t.Errorf("query wrongly decided to be non-empty (error %v)", err)
+	}
+}
+
+//TestCount checks the counting of answers, with and without free vertices, against the expected numbers
+func TestCount(t *testing.T) {
+	graph, encoding, decomp, db := triangleQuery(t)
+
+	tests := []struct {
+		free     []string
+		expected int64
+	}{
+		{nil, 4},
+		{[]string{"x", "y", "z", "w"}, 4},
+		{[]string{"x", "w"}, 2},
+		{[]string{"x"}, 1},
+		{[]string{"y"}, 2},
+		{[]string{"y", "w"}, 4}, // x is shared between both nodes, needs enumeration
+		{[]string{"w"}, 2},
+	}
+
+	for _, test := range tests {
+		var free []int
+		for _, name := range test.free {
+			v, _ := encoding.ID(name)
+			free = append(free, v)
+		}
+
+		count, err := logk.Count(decomp, graph, db, free)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count.Int64() != test.expected {
+			t.Errorf("free vertices %v: expected %v answers, counted %v", test.free, test.expected, count)
+		}
+	}
+}
+
+//TestToSQL checks the structure of the SQL exported for a decomposition, in both dialects
+func TestToSQL(t *testing.T) {
+	graph, encoding, decomp, _ := triangleQuery(t)
+	mapping := logk.SQLMapping{"R": {Table: "edges", Columns: []string{"src", "dst"}}}
+
+	postgres, err := logk.ToSQL(decomp, graph, encoding, mapping, logk.PostgreSQL, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlite, err := logk.ToSQL(decomp, graph, encoding, mapping, logk.SQLite, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(postgres, "AS MATERIALIZED") || strings.Contains(sqlite, "MATERIALIZED") {
+		t.Error("dialects not respected")
+	}
+	if !strings.Contains(postgres, `"edges" t0`) || !strings.Contains(postgres, `t0."src" AS "x"`) {
+		t.Errorf("mapping not respected:\n%v", postgres)
+	}
+	// V(w,w) has to be checked in the node covering it, and enforce equality on its columns
+	if !strings.Contains(postgres, `"V" t`) {
+		t.Errorf("edge not in any cover is not checked:\n%v", postgres)
+	}
+	if !strings.HasSuffix(sqlite, "SELECT EXISTS (SELECT 1 FROM \"u0\");\n") {
+		t.Errorf("boolean query doesn't end with existence check:\n%v", sqlite)
+	}
+
+	mapping["S"] = logk.SQLTable{Table: "s", Columns: []string{"a"}}
+	if _, err := logk.ToSQL(decomp, graph, encoding, mapping, logk.SQLite, nil, false); err == nil {
+		t.Error("mapping with wrong number of columns not rejected")
+	}
+}
+
+// triangleScript produces a script creating the tables of the triangle query, as given by the mapping, followed
+// by the statements
+func triangleScript(mapping logk.SQLMapping, statements ...string) string {
+	var sb strings.Builder
+
+	for name, data := range triangleData {
+		table, columns := mapping[name].Table, mapping[name].Columns
+
+		fmt.Fprintf(&sb, "CREATE TABLE %q (%q INTEGER, %q INTEGER);\n", table, columns[0], columns[1])
+		for _, row := range strings.Fields(data) {
+			fmt.Fprintf(&sb, "INSERT INTO %q VALUES (%v);\n", table, row)
+		}
+	}
+	for _, statement := range statements {
+		sb.WriteString(statement + "\n")
+	}
+
+	return sb.String()
+}
+
+// runSQLite runs the script on an in-memory database with the sqlite3 shell, returning the rows it outputs. The
+// test is skipped if the shell isn't installed.
+func runSQLite(t *testing.T, script string) []string {
+	path, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Skip("sqlite3 not found, skipping the execution of SQL queries")
+	}
+
+	cmd := exec.Command(path, "-bail", ":memory:")
+	cmd.Stdin = strings.NewReader(script)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("script not accepted by SQLite: %v\n%s\n%v", err, out, script)
+	}
+
+	output := strings.TrimSpace(string(out))
+	if output == "" {
+		return nil
+	}
+	return strings.Split(output, "\n")
+}
+
+//TestToSQLExecute runs the exported queries with the sqlite3 shell, and compares their results with those of
+// Evaluate
+func TestToSQLExecute(t *testing.T) {
+	graph, encoding, decomp, rels := triangleQuery(t)
+	mapping := logk.SQLMapping{
+		"R": {Table: "edges", Columns: []string{"src", "dst"}},
+		"S": {Table: "S", Columns: []string{"y", "z"}},
+		"T": {Table: "T", Columns: []string{"z", "x"}},
+		"U": {Table: "U", Columns: []string{"x", "w"}},
+		"V": {Table: "V", Columns: []string{"w1", "w2"}}, // a table can't have two columns named w
+	}
+
+	x, _ := encoding.ID("x")
+	w, _ := encoding.ID("w")
+
+	for _, free := range [][]int{nil, {x, w}} {
+		query, err := logk.ToSQL(decomp, graph, encoding, mapping, logk.SQLite, free, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		answers, err := logk.Evaluate(decomp, graph, rels, free)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if rows := runSQLite(t, triangleScript(mapping, query)); len(rows) != answers.Len() {
+			t.Errorf("SQL query with free vertices %v has %d answers, Evaluate %d:\n%v", free, len(rows),
+				answers.Len(), query)
+		}
+	}
+
+	query, err := logk.ToSQL(decomp, graph, encoding, mapping, logk.SQLite, nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows := runSQLite(t, triangleScript(mapping, query)); len(rows) != 1 || rows[0] != "1" {
+		t.Errorf("boolean SQL query wrongly decided to be empty, output %v:\n%v", rows, query)
+	}
+	if rows := runSQLite(t, triangleScript(mapping, `DELETE FROM "U";`, query)); len(rows) != 1 || rows[0] != "0" {
+		t.Errorf("boolean SQL query wrongly decided to be non-empty, output %v:\n%v", rows, query)
+	}
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+// edgeNames lists the edges of a graph, each as its name followed by the names of its vertices
+func edgeNames(g lib.Graph, encoding logk.Encoding) []string {
+	var output []string
+	for _, e := range g.Edges.Slice() {
+		output = append(output, encoding.Edge(lib.Edge{Name: e.Name})+encoding.Vertices(e.Vertices))
+	}
+	return output
+}
+
+//TestFormatsRoundTrip converts hypergraphs between all pairs of formats, checking that the names are kept by the
+// named formats, and that the numeric ones keep the structure and list the names lost
+func TestFormatsRoundTrip(t *testing.T) {
+	input := "R(x,y), S(y,z,\"w w\"), T(z,x), U(x)."
+	graph, encoding, err := logk.GetGraph(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, from := range logk.FormatNames() {
+		source, _ := logk.GetFormat(from)
+		var buffer bytes.Buffer
+		if err := source.Write(&buffer, graph, encoding); err != nil {
+			if from == "gr" {
+				continue // the edge S is not part of a graph
+			}
+			t.Fatalf("%s: %v", from, err)
+		}
+		read, readEncoding, err := source.Read(buffer.String())
+		if err != nil {
+			t.Fatalf("%s: can't read back %q: %v", from, buffer.String(), err)
+		}
+
+		if source.Numeric {
+			if read.Edges.Len() != graph.Edges.Len() || len(read.Vertices()) != len(graph.Vertices()) {
+				t.Errorf("%s: structure changed, got %v", from, readEncoding.Graph(read))
+			}
+			if !strings.Contains(buffer.String(), "c vertex 4 w w") || !strings.Contains(buffer.String(), "c edge 2 S") {
+				t.Errorf("%s: name map missing in %q", from, buffer.String())
+			}
+			continue
+		}
+		if got, expected := edgeNames(read, readEncoding), edgeNames(graph, encoding); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %v, got %v", from, expected, got)
+		}
+	}
+
+	// graphs read from a numeric format keep their numbers, without a name map
+	pace := "p htd 3 2\n2 1 2\n1 2 3\n"
+	graph, encoding, _ = logk.GetGraphPACE(pace)
+	var buffer bytes.Buffer
+	logk.WritePACE(&buffer, graph, encoding)
+	if buffer.String() != pace {
+		t.Errorf("expected %q, got %q", pace, buffer.String())
+	}
+
+	// names of the form V<i> only keep their number if these are exactly the numbers 1 to n
+	for input, header := range map[string]string{"E1(V1,V01).": "p htd 2 1\n", "E1(V2,V7), E2(V7,V9).": "p htd 3 2\n"} {
+		graph, encoding, _ = logk.GetGraph(input)
+		buffer.Reset()
+		logk.WritePACE(&buffer, graph, encoding)
+		if !strings.Contains(buffer.String(), header) || !strings.Contains(buffer.String(), "c vertex 2 V") {
+			t.Errorf("%s: expected renumbered vertices, got %q", input, buffer.String())
+		}
+
+		buffer.Reset()
+		decomp := lib.Decomp{Graph: graph, Root: lib.Node{Bag: graph.Vertices(), Cover: graph.Edges}}
+		logk.WriteTD(&buffer, decomp, graph, encoding)
+		n := strings.Fields(header)[2]
+		if td := fmt.Sprintf("s td 1 %s %s\n", n, n); !strings.Contains(buffer.String(), td) {
+			t.Errorf("%s: expected %q in %q", input, td, buffer.String())
+		}
+	}
+
+	if _, _, err := logk.GetGraphJSON(`{"edges": [{"name": "R", "vertices": []}]}`); err == nil {
+		t.Error("edge without vertices not rejected")
+	}
+	if _, err := logk.GetFormat("xml"); err == nil {
+		t.Error("unknown format not rejected")
+	}
+}
+
+//TestFormatsNormalise checks sorting and renumbering, and the name map listing the names replaced
+func TestFormatsNormalise(t *testing.T) {
+	graph, encoding, err := logk.GetGraph("E10(c,a), E2(b,a), E1(c).")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sorted := logk.SortGraph(graph, encoding)
+	if got := edgeNames(sorted, encoding); !reflect.DeepEqual(got, []string{"E1(c)", "E2(a, b)", "E10(a, c)"}) {
+		t.Errorf("unexpected order %v", got)
+	}
+	if got := edgeNames(graph, encoding); got[0] != "E10(c, a)" {
+		t.Errorf("sorting modified the graph: %v", got)
+	}
+
+	renumbered := logk.Renumber(sorted)
+	if got := edgeNames(sorted, renumbered); !reflect.DeepEqual(got, []string{"E1(V1)", "E2(V2, V3)", "E3(V2, V1)"}) {
+		t.Errorf("unexpected names %v", got)
+	}
+	if got := edgeNames(logk.SortGraph(sorted, renumbered), renumbered); got[2] != "E3(V1, V2)" {
+		t.Errorf("vertices not sorted by their new names: %v", got)
+	}
+
+	expected := []string{"vertex 1 c", "vertex 2 a", "vertex 3 b", "edge 1 E1", "edge 2 E2", "edge 3 E10"}
+	if got := logk.NameMap(sorted, renumbered, encoding); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected name map %v, got %v", expected, got)
+	}
+}
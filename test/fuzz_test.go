@@ -0,0 +1,277 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	"github.com/cem-okulmus/disjoint"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+// fuzzPipelines are the preprocessing pipelines tried by FuzzDecompose, chosen by the fuzzer
+var fuzzPipelines = [][]string{
+	{},
+	{"gyo"},
+	{"typecollapse", "gyo"},
+	{"dedup", "subsume", "degree1"},
+	{"dedup", "subsume", "degree1", "typecollapse", "gyo"},
+}
+
+// fuzzDecompose decomposes the graph at width k after applying the pipeline, restoring the decomposition
+func fuzzDecompose(t *testing.T, graph lib.Graph, k int, pipeline []string, solver interface {
+	SetWidth(int)
+	FindDecompGraph(lib.Graph) lib.Decomp
+}) lib.Decomp {
+	reduced := graph
+	var reductions []logk.Reduction
+
+	for _, step := range pipeline {
+		var reduction logk.Reduction
+		var err error
+		if reduced, reduction, err = logk.Preprocess(step, reduced); err != nil {
+			t.Fatal(err)
+		}
+		reductions = append(reductions, reduction)
+	}
+
+	var decomp lib.Decomp
+	if reduced.Edges.Len() > 0 {
+		solver.SetWidth(k)
+		decomp = solver.FindDecompGraph(lib.Graph{Edges: reduced.Edges})
+		if reflect.DeepEqual(decomp, lib.Decomp{}) {
+			return decomp
+		}
+	}
+
+	for i := len(reductions) - 1; i >= 0; i-- {
+		var ok bool
+		if decomp.Root, ok = reductions[i].Restore(decomp.Root); !ok {
+			t.Fatalf("restoring %v failed for %v", pipeline[i], graph)
+		}
+	}
+	decomp.Graph = graph
+
+	return decomp
+}
+
+//FuzzDecompose parses HyperBench or PACE input, and decomposes it at a small width with LogKDecomp and DetKDecomp,
+// after some preprocessing. Neither may panic, they must agree on whether an HD exists, and each HD must be correct.
+func FuzzDecompose(f *testing.F) {
+	f.Add("E1(a,b),E2(b,c),E3(c,a).", uint8(1), uint8(0), false)
+	f.Add("E1(a,b,c),E2(c,d),E3(d,e,a),E4(e,b),E5(a,c).", uint8(2), uint8(1), false)
+	f.Add("R(x,y),S(y,z),T(z,w),U(w,x),V(x,z).", uint8(1), uint8(4), false)
+	f.Add("E1(a,b),E2(a,b),E3(b,c,d),E4(d).", uint8(0), uint8(3), false)
+	f.Add("p htd 4 3\n1 1 2\n2 2 3 4\n3 4 1\n", uint8(1), uint8(2), true)
+
+	f.Fuzz(func(t *testing.T, input string, width uint8, pipelineChoice uint8, pace bool) {
+		var graph lib.Graph
+		var err error
+		if pace {
+			graph, _, err = logk.GetGraphPACE(input)
+		} else {
+			graph, _, err = logk.GetGraph(input)
+		}
+		if err != nil || graph.Edges.Len() == 0 || graph.Edges.Len() > 10 || len(graph.Vertices()) > 16 {
+			return // keep to small instances, which can be decomposed quickly
+		}
+
+		k := int(width%3) + 1
+		pipeline := fuzzPipelines[int(pipelineChoice)%len(fuzzPipelines)]
+
+		logK := &logk.LogKDecomp{BalFactor: 2}
+		logK.SetGenerator(lib.ParallelSearchGen{})
+		detK := &logk.DetKDecomp{BalFactor: 2}
+
+		var found []bool
+		for _, solver := range []interface {
+			SetWidth(int)
+			FindDecompGraph(lib.Graph) lib.Decomp
+		}{logK, detK} {
+			decomp := fuzzDecompose(t, graph, k, pipeline, solver)
+			if reflect.DeepEqual(decomp, lib.Decomp{}) {
+				found = append(found, false)
+				continue
+			}
+
+			if !decomp.Correct(graph) {
+				t.Fatalf("incorrect HD with pipeline %v of %v:\n%v", pipeline, graph, decomp)
+			}
+			if decomp.CheckWidth() > k {
+				t.Fatalf("HD of width %d > %d with pipeline %v of %v:\n%v", decomp.CheckWidth(), k, pipeline,
+					graph, decomp)
+			}
+			found = append(found, true)
+		}
+
+		if found[0] != found[1] {
+			t.Fatalf("width %d with pipeline %v of %v: found by LogKDecomp %v, by DetKDecomp %v", k, pipeline,
+				graph, found[0], found[1])
+		}
+	})
+}
+
+// fuzzReader turns the bytes produced by the fuzzer into small numbers
+type fuzzReader struct {
+	data []byte
+	pos  int
+}
+
+// next returns a number below n, or 0 once the bytes are used up
+func (r *fuzzReader) next(n int) int {
+	if r.pos >= len(r.data) {
+		return 0
+	}
+	r.pos++
+	return int(r.data[r.pos-1]) % n
+}
+
+// subset selects some of the values
+func (r *fuzzReader) subset(values []int) []int {
+	var output []int
+	for _, v := range values {
+		if r.next(2) == 1 {
+			output = append(output, v)
+		}
+	}
+	return output
+}
+
+// referenceParent decides the conditions of ParentCheck with balance factor 2 by brute force: some component of H
+// without the vertices of sep has more than half of the edges of H, all its connecting vertices are covered by sep,
+// and all its vertices in sep belong to the child
+func referenceParent(H lib.Graph, sep lib.Edges, conn []int, child []int) bool {
+	sepVertices := make(map[int]bool)
+	for _, v := range sep.Vertices() {
+		sepVertices[v] = true
+	}
+
+	// the edges of H, including special edges, by their vertices
+	var edges [][]int
+	for _, e := range H.Edges.Slice() {
+		edges = append(edges, e.Vertices)
+	}
+	for _, sp := range H.Special {
+		edges = append(edges, sp.Vertices())
+	}
+
+	// components, as sets of edges, by repeatedly adding edges sharing a vertex outside of sep
+	component := make([]int, len(edges))
+	for i := range component {
+		component[i] = -1
+	}
+	count := 0
+	for i := range edges {
+		outside := false
+		for _, v := range edges[i] {
+			outside = outside || !sepVertices[v]
+		}
+		if !outside || component[i] != -1 {
+			continue // covered by sep, or already in a component
+		}
+
+		component[i] = count
+		for changed := true; changed; {
+			changed = false
+			for j := range edges {
+				if component[j] != -1 {
+					continue
+				}
+				for _, v := range edges[j] {
+					for l := range edges {
+						if component[l] == count && !sepVertices[v] && len(lib.Inter(edges[l], []int{v})) > 0 {
+							component[j] = count
+							changed = true
+						}
+					}
+				}
+			}
+		}
+		count++
+	}
+
+	for c := 0; c < count; c++ {
+		size := 0
+		var vertices []int
+		for i := range edges {
+			if component[i] == c {
+				size++
+				vertices = append(vertices, edges[i]...)
+			}
+		}
+		if 2*size <= len(edges) {
+			continue
+		}
+
+		for _, v := range lib.Inter(vertices, conn) {
+			if !sepVertices[v] {
+				return false
+			}
+		}
+		for _, v := range vertices {
+			if sepVertices[v] && len(lib.Inter(child, []int{v})) == 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+//FuzzParentCheck compares ParentCheck against a brute-force implementation of the parent conditions, on small
+// hypergraphs with special edges, separators, connecting vertices and children built from the fuzzed bytes
+func FuzzParentCheck(f *testing.F) {
+	f.Add([]byte{3, 2, 1, 2, 2, 2, 3, 2, 3, 1, 1, 0, 0, 1, 0, 1, 1, 1, 0, 1, 1})
+	f.Add([]byte{5, 1, 0, 1, 1, 1, 2, 2, 3, 4, 1, 5, 2, 0, 6, 1, 1, 0, 0, 1, 1, 0, 1, 0, 1, 1, 0, 1, 1})
+	f.Add([]byte{7, 0, 0, 1, 1, 1, 2, 1, 3, 1, 4, 1, 5, 1, 6, 0, 1, 0, 0, 0, 0, 0, 1, 1, 1, 1, 1, 1, 1, 1, 1})
+	f.Add([]byte{2, 1, 0, 1, 1, 1, 2, 2, 1, 2, 3, 1, 1, 3, 4, 0, 1, 0, 1, 1, 0, 1, 1, 0, 1, 1})
+	f.Add([]byte{1, 1, 0, 1, 3, 2, 1, 2, 3, 3, 1, 2, 5, 1, 4, 5, 1, 0, 1, 0, 0, 1, 1, 1, 1, 0})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzReader{data: data}
+
+		var edges []lib.Edge
+		n := r.next(8) + 1
+		for i := 0; i < n; i++ {
+			arity := r.next(3) + 1
+			var vertices []int
+			for j := 0; j < arity; j++ {
+				vertices = append(vertices, r.next(8)+1)
+			}
+			edges = append(edges, lib.Edge{Name: 100 + i, Vertices: lib.RemoveDuplicates(vertices)})
+		}
+		H := lib.Graph{Edges: lib.NewEdges(edges)}
+
+		// special edges, as created for the children of parents, consist of a single edge without a name
+		special := r.next(4)
+		for i := 0; i < special; i++ {
+			arity := r.next(3) + 1
+			var vertices []int
+			for j := 0; j < arity; j++ {
+				vertices = append(vertices, r.next(8)+1)
+			}
+			H.Special = append(H.Special, lib.NewEdges([]lib.Edge{{Vertices: lib.RemoveDuplicates(vertices)}}))
+		}
+
+		var sepEdges []lib.Edge
+		for _, e := range edges {
+			if r.next(2) == 1 {
+				sepEdges = append(sepEdges, e)
+			}
+		}
+		sep := lib.NewEdges(sepEdges)
+		vertices := append([]int{}, H.Vertices()...)
+		conn := r.subset(vertices)
+		child := r.subset(vertices)
+
+		check := logk.ParentCheck{Conn: conn, Child: child}
+		got := check.Check(&H, &sep, 2, make(map[int]*disjoint.Element))
+		expected := referenceParent(H, sep, conn, child)
+
+		if got != expected {
+			t.Fatalf("H %v, sep %v, conn %v, child %v: ParentCheck %v, reference %v", H, sep, conn, child, got,
+				expected)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package tests
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	"github.com/cem-okulmus/disjoint"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+//TestGenerateSeeded checks that the random generators produce the same hypergraphs for the same seed, of the
+// requested size, and that both output formats parse to the same hypergraph
+func TestGenerateSeeded(t *testing.T) {
+	generators := map[string]func(r *rand.Rand) (logk.Generated, error){
+		"random": func(r *rand.Rand) (logk.Generated, error) { return logk.RandomHypergraph(r, 12, 9, 4) },
+		"ktree":  func(r *rand.Rand) (logk.Generated, error) { return logk.KTree(r, 12, 3) },
+		"query":  func(r *rand.Rand) (logk.Generated, error) { return logk.RandomQuery(r, 9, 4, 12) },
+	}
+	edges := map[string]int{"random": 9, "ktree": 6 + 8*3, "query": 9}
+
+	for name, generate := range generators {
+		first, err := generate(rand.New(rand.NewSource(42)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, _ := generate(rand.New(rand.NewSource(42)))
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("%s: same seed produced different hypergraphs:\n%v\n%v", name, first.HyperBench(),
+				second.HyperBench())
+		}
+		if len(first.Edges) != edges[name] {
+			t.Errorf("%s: expected %d edges, got %d", name, edges[name], len(first.Edges))
+		}
+		for _, e := range first.Edges {
+			if len(e) == 0 || len(e) > 4 || len(lib.RemoveDuplicates(append([]int{}, e...))) != len(e) {
+				t.Errorf("%s: malformed edge %v", name, e)
+			}
+		}
+
+		graph, _, err := first.Graph()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pace, _, err := logk.GetGraphPACE(first.PACE())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if graph.ToHyberBenchFormat() != pace.ToHyberBenchFormat() {
+			t.Errorf("%s: formats differ:\n%v\n%v", name, first.HyperBench(), first.PACE())
+		}
+		comps, _, _ := graph.GetComponents(lib.Edges{}, make(map[int]*disjoint.Element))
+		if name == "query" && len(comps) != 1 {
+			t.Errorf("query is not connected:\n%v", first.HyperBench())
+		}
+	}
+}
+
+//TestGenerateWidths checks the widths claimed for the structured generators: an HD exists of the hypertree width,
+// but none below it, and the min-fill heuristic, an upper bound, finds a tree decomposition of the treewidth
+func TestGenerateWidths(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	var instances []logk.Generated
+	add := func(g logk.Generated, err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		instances = append(instances, g)
+	}
+	add(logk.Cycle(7))
+	add(logk.Clique(5))
+	add(logk.Clique(6))
+	add(logk.Grid(1, 6))
+	add(logk.Grid(3, 4))
+	add(logk.KTree(r, 10, 1))
+	add(logk.KTree(r, 10, 3))
+
+	for _, g := range instances {
+		graph, _, err := g.Graph()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fill := logk.TreeWidth(logk.MinFill(graph))
+		if fill != g.TreeWidth {
+			t.Errorf("%s: treewidth %d, but min-fill gives %d", g.Description, g.TreeWidth, fill)
+		}
+
+		if g.HyperWidth < 0 {
+			continue
+		}
+		for k := g.HyperWidth - 1; k <= g.HyperWidth; k++ {
+			if k < 1 {
+				continue
+			}
+			solver := &logk.DetKDecomp{BalFactor: 2}
+			solver.SetWidth(k)
+			decomp := solver.FindDecompGraph(lib.Graph{Edges: graph.Edges})
+			if found := !reflect.DeepEqual(decomp, lib.Decomp{}); found != (k == g.HyperWidth) {
+				t.Errorf("%s: hypertree width %d, but HD of width %d found: %v", g.Description, g.HyperWidth, k,
+					found)
+			}
+		}
+	}
+
+	if _, err := logk.Grid(1, 1); err == nil {
+		t.Error("grid without edges not rejected")
+	}
+	if _, err := logk.KTree(r, 3, 3); err == nil {
+		t.Error("k-tree with too few vertices not rejected")
+	}
+}
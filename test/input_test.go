@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+	"github.com/klauspost/compress/zstd"
+)
+
+// TestInputDecompression ensures that gzip and zstd compressed input is detected and restored
+func TestInputDecompression(t *testing.T) {
+	input := []byte("R(x,y),\nS(y,z).\n")
+
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(input)
+	w.Close()
+
+	enc, _ := zstd.NewWriter(nil)
+	zst := enc.EncodeAll(input, nil)
+
+	for _, compressed := range [][]byte{input, gz.Bytes(), zst} {
+		out, err := logk.Decompress(compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, input) {
+			t.Errorf("decompression produced %q, expected %q", out, input)
+		}
+	}
+}
+
+// TestSplitDocuments ensures that inputs with several hypergraphs are split correctly
+func TestSplitDocuments(t *testing.T) {
+	hyperbench := "% first graph\nR(x,y),\nS(y,z).\n\n% second graph, with a comment ending in ).\nT(a,b). % trailing\nU(c,d),\nV(d,e)."
+
+	docs := logk.SplitDocuments(hyperbench, false)
+	if len(docs) != 3 {
+		t.Fatalf("expected 3 documents, got %v: %q", len(docs), docs)
+	}
+	for _, doc := range docs {
+		if _, _, err := logk.GetGraph(doc); err != nil {
+			t.Error(err)
+		}
+	}
+
+	pace := "c first\np htd 2 1\n1 1 2\np htd 3 2\n1 1 2\n2 2 3\n"
+	docs = logk.SplitDocuments(pace, true)
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 documents, got %v: %q", len(docs), docs)
+	}
+	graph, _, err := logk.GetGraphPACE(docs[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if graph.Edges.Len() != 2 {
+		t.Errorf("expected 2 edges, got %v", graph.Edges.Len())
+	}
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+
+	algo "github.com/cem-okulmus/BalancedGo/algorithms"
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+//TestMemoryGuard checks the levels of the memory guard: near the limit the caches are asked to empty, and fewer
+// workers used if that wasn't enough, while beyond the limit the searches are stopped for good
+func TestMemoryGuard(t *testing.T) {
+	cpus := runtime.GOMAXPROCS(0)
+
+	var none *logk.MemoryGuard
+	if none.High() || none.Exceeded() || none.Flag() != nil || none.Evictions() != 0 || none.Workers(4) != 4 {
+		t.Error("nil guard reports pressure")
+	}
+
+	guard := logk.NewMemoryGuard(1000)
+	guard.Check(500)
+	if guard.High() || guard.Evictions() != 0 {
+		t.Error("guard reports pressure far below the limit")
+	}
+
+	guard.Check(850)
+	if !guard.High() || guard.Exceeded() || guard.Evictions() != 1 || guard.Workers(4) != 4 {
+		t.Errorf("expected only an eviction near the limit, got %v", guard)
+	}
+	guard.Check(900)
+	if guard.Evictions() != 2 || guard.Workers(4) != 2 || guard.Workers(1) != 1 {
+		t.Errorf("expected the workers to be halved while still near the limit, got %v", guard)
+	}
+	if runtime.GOMAXPROCS(0) != cpus {
+		t.Errorf("guard changed the CPUs of the process from %d to %d", cpus, runtime.GOMAXPROCS(0))
+	}
+
+	guard.Check(100)
+	if guard.High() {
+		t.Error("guard reports pressure after the memory was freed")
+	}
+
+	guard.Check(1000)
+	guard.Check(100)
+	if !guard.Exceeded() || !guard.Flag().Stopped() || !guard.Flag().Child().Stopped() {
+		t.Errorf("expected the guard to stay exceeded, got %v", guard)
+	}
+}
+
+//TestMemoryDegradation checks that the algorithms still find correct HDs when memory runs low, emptying their
+// caches and falling back to DetKDecomp, and give up once the limit is exceeded
+func TestMemoryDegradation(t *testing.T) {
+	generated, _ := logk.Clique(6)
+	graph, _, err := generated.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := generated.HyperWidth
+
+	guard := logk.NewMemoryGuard(1000)
+	solvers := []algo.Algorithm{
+		&logk.LogKDecomp{BalFactor: 2, Memory: guard},
+		&logk.LogKHybrid{BalFactor: 2, Size: 3, Memory: guard},
+		&logk.DetKDecomp{BalFactor: 2, Parallel: true, Memory: guard},
+	}
+	solvers[1].(*logk.LogKHybrid).Predicate = solvers[1].(*logk.LogKHybrid).NumberEdgesPred
+
+	for _, solver := range solvers {
+		solver.SetGenerator(lib.ParallelSearchGen{})
+		solver.SetWidth(k)
+		if decomp := solver.FindDecompGraph(graph); !decomp.Correct(graph) {
+			t.Fatalf("%s: no HD of width %d found without pressure", solver.Name(), k)
+		}
+	}
+
+	guard.Check(900)
+	for _, solver := range solvers {
+		solver.SetWidth(k)
+		decomp := solver.FindDecompGraph(graph)
+		if !decomp.Correct(graph) || decomp.CheckWidth() > k {
+			t.Errorf("%s: no correct HD of width %d found near the limit:\n%v", solver.Name(), k, decomp)
+		}
+		if stats := solver.(interface{ CacheStats() logk.CacheStats }).CacheStats(); stats.Evictions == 0 {
+			t.Errorf("%s: cache not emptied near the limit", solver.Name())
+		}
+	}
+
+	guard.Check(1000)
+	for _, solver := range solvers {
+		solver.SetWidth(k)
+		if decomp := solver.FindDecompGraph(graph); !reflect.DeepEqual(decomp, lib.Decomp{}) {
+			t.Errorf("%s: search not stopped beyond the limit", solver.Name())
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+//TestNormalise checks that normalisation removes redundant nodes and edges, and re-roots the decomposition,
+// while keeping it correct
+func TestNormalise(t *testing.T) {
+	graph, encoding, err := logk.GetGraph("R(a,b), S(b,c), T(c,d), U(d,e).")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := graph.Edges.Slice()
+	bag := func(slice ...lib.Edge) []int {
+		edges := lib.NewEdges(slice)
+		return edges.Vertices()
+	}
+
+	// a path of nodes, with a redundant node at the root, a leaf contained in its parent and an oversized cover
+	leaf := lib.Node{Bag: e[3].Vertices[1:], Cover: lib.NewEdges(e[3:4])}
+	nodeU := lib.Node{Bag: bag(e[3]), Cover: lib.NewEdges(e[2:4]), Children: []lib.Node{leaf}}
+	nodeT := lib.Node{Bag: bag(e[2]), Cover: lib.NewEdges(e[2:3]), Children: []lib.Node{nodeU}}
+	nodeRS := lib.Node{Bag: bag(e[0], e[1]), Cover: lib.NewEdges(e[0:2]), Children: []lib.Node{nodeT}}
+	decomp := lib.Decomp{Graph: graph, Root: lib.Node{Bag: e[0].Vertices, Cover: lib.NewEdges(e[0:1]),
+		Children: []lib.Node{nodeRS}}}
+	if !decomp.Correct(graph) {
+		t.Fatal("hand-made decomposition not correct")
+	}
+
+	normal, report := logk.Normalise(decomp, logk.NormaliseOptions{RemoveRedundant: true, MinimiseCovers: true})
+	if !normal.Correct(graph) || normal.CheckWidth() > decomp.CheckWidth() {
+		t.Errorf("normalisation produced invalid decomposition:\n%v", encoding.Decomp(normal))
+	}
+	if report.RemovedNodes != 2 || report.RemovedEdges != 1 || report.OldDepth != 4 || report.NewDepth != 2 {
+		t.Errorf("unexpected changes: %+v\n%v", report, encoding.Decomp(normal))
+	}
+
+	e4, _ := encoding.ID("e")
+	rooted, report := logk.Normalise(normal, logk.NormaliseOptions{RootVertices: []int{e4}})
+	if !rooted.Correct(graph) || !report.Rerooted || len(report.MissingAtRoot) > 0 {
+		t.Errorf("re-rooting failed: %+v\n%v", report, encoding.Decomp(rooted))
+	}
+
+	centered, report := logk.Normalise(rooted, logk.NormaliseOptions{MinimiseDepth: true})
+	if !centered.Correct(graph) || report.NewDepth != 1 {
+		t.Errorf("minimising depth failed: %+v\n%v", report, encoding.Decomp(centered))
+	}
+}
@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+//TestPreprocess checks that each preprocessing step shrinks the hypergraph as expected, and that decompositions
+// of the reduced hypergraph are restored to ones of the original
+func TestPreprocess(t *testing.T) {
+	graph, encoding, err := logk.GetGraph("R(a,b,x), S(b,c), T(c,a), D(c,b), U(a), V(y,z).")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][2]int{ // vertices and edges after each step
+		"dedup":        {6, 5},
+		"subsume":      {6, 5},
+		"degree1":      {4, 6},
+		"typecollapse": {5, 6},
+		"gyo":          {3, 3},
+	}
+
+	for step, sizes := range expected {
+		reduced, reduction, err := logk.Preprocess(step, graph)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reduced.Vertices()) != sizes[0] || reduced.Edges.Len() != sizes[1] {
+			t.Errorf("%s: wrong reduced graph %v", step, encoding.Graph(reduced))
+			continue
+		}
+
+		// a decomposition of the reduced graph, with a node for each component
+		var decomps []lib.Decomp
+		for _, c := range logk.ConnectedComponents(reduced) {
+			decomps = append(decomps, lib.Decomp{Root: lib.Node{Bag: c.Vertices(), Cover: c.Edges}})
+		}
+		decomp := logk.JoinDecomps(reduced, decomps)
+
+		root, ok := reduction.Restore(decomp.Root)
+		restored := lib.Decomp{Graph: graph, Root: root}
+		if !ok || !restored.Correct(graph) {
+			t.Errorf("%s: restoring failed:\n%v", step, encoding.Decomp(restored))
+		}
+	}
+
+	if _, err := logk.ParsePipeline("dedup, gyo,unknown"); err == nil {
+		t.Error("unknown step not rejected")
+	}
+}
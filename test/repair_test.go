@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+//TestRepair checks that decompositions are repaired after adding and removing edges, reusing the parts of the
+// tree not affected, and that a full search is done if no repair of the width exists
+func TestRepair(t *testing.T) {
+	original := "R(a,b), S(b,c), T(c,d), U(d,e), V(e,f), W(f,g), X(g,h), Y(h,a), Z(a,i)."
+	graph, encoding, err := logk.GetGraph(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	solver := &logk.DetKDecomp{Graph: graph, BalFactor: 2}
+	solver.SetWidth(2)
+	old := solver.FindDecomp()
+	old.Graph = graph
+	if !old.Correct(graph) {
+		t.Fatal("no HD found")
+	}
+	before := encoding.Decomp(old)
+
+	tests := []struct {
+		description string
+		graph       string
+		width       int
+		fullSearch  bool
+	}{
+		{"unchanged", original, 2, false},
+		{"edge added", original[:len(original)-1] + ", Q(c,j).", 2, false},
+		{"chord added", original[:len(original)-1] + ", Q(b,f).", 2, false},
+		{"edge removed", "R(a,b), S(b,c), T(c,d), U(d,e), V(e,f), W(f,g), X(g,h), Y(h,a).", 2, false},
+		{"edge changed", "R(a,b), S(b,c), T(c,d), U(d,e), V(e,f), W(f,g), X(g,h), Y(h,a), Z(i,j).", 2, false},
+		{"width too small", original, 1, true},
+	}
+
+	for _, test := range tests {
+		parsed, parsedEncoding, err := logk.GetGraph(test.graph)
+		if err != nil {
+			t.Fatal(err)
+		}
+		modified, _ := logk.AlignEncoding(parsed, parsedEncoding, encoding)
+
+		repairer := &logk.DetKDecomp{Graph: modified, BalFactor: 2}
+		repairer.SetWidth(test.width)
+		repaired, report := repairer.Repair(old)
+
+		if report.FullSearch != test.fullSearch {
+			t.Errorf("%s: expected full search %v, got %v", test.description, test.fullSearch, report)
+		}
+		if test.fullSearch {
+			if !reflect.DeepEqual(repaired, lib.Decomp{}) {
+				t.Errorf("%s: unexpected HD of width %d", test.description, test.width)
+			}
+			continue
+		}
+
+		if !repaired.Correct(modified) || repaired.CheckWidth() > test.width {
+			t.Errorf("%s: repaired HD not correct: %v", test.description, encoding.Decomp(repaired))
+		}
+		if report.Reused == 0 || report.Reused > report.Nodes {
+			t.Errorf("%s: no part of the tree reused: %v", test.description, report)
+		}
+		if test.description == "unchanged" && report.Reused != report.Nodes {
+			t.Errorf("%s: expected the whole tree to be reused, got %v", test.description, report)
+		}
+		if test.description == "edge added" && report.Reused != report.Nodes-1 {
+			t.Errorf("%s: expected a new leaf below the old tree, got %v", test.description, report)
+		}
+	}
+
+	if after := encoding.Decomp(old); after != before {
+		t.Errorf("old HD was modified, from %v to %v", before, after)
+	}
+}
@@ -0,0 +1,228 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+	"github.com/cem-okulmus/log-k-decomp/service"
+)
+
+// serviceCall sends a request to the service, decoding the JSON response into out
+func serviceCall(t *testing.T, method string, url string, body interface{}, out interface{}) int {
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	req, err := http.NewRequest(method, url, &payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+// awaitJob polls the status of a job until it is finished
+func awaitJob(t *testing.T, url string, id string) service.Status {
+	deadline := time.Now().Add(30 * time.Second)
+
+	for {
+		var status service.Status
+		if code := serviceCall(t, http.MethodGet, url+"/jobs/"+id, nil, &status); code != http.StatusOK {
+			t.Fatalf("job %s: status %d", id, code)
+		}
+		if status.State != service.Queued && status.State != service.Running {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s not finished: %+v", id, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// hardInstance produces a hypergraph for which the search at width 3 takes several seconds
+func hardInstance() string {
+	generated, _ := logk.RandomHypergraph(rand.New(rand.NewSource(3)), 40, 40, 4)
+	return generated.HyperBench()
+}
+
+//TestServiceDecompose checks that jobs submitted in each format are decomposed, in exact mode and with
+// preprocessing, and that invalid requests are refused
+func TestServiceDecompose(t *testing.T) {
+	s := service.New(service.Config{Workers: 2, QueueSize: 8})
+	defer s.Close()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	requests := []service.Request{
+		{Graph: "E1(a,b),E2(b,c),E3(c,a).", Width: 2},
+		{Graph: "E1(a,b),E2(b,c),E3(c,a).", Width: 1, Algorithm: "detk"},
+		{Graph: "p htd 4 4\n1 1 2\n2 2 3\n3 3 4\n4 4 1\n", Format: "pace", Exact: true, Algorithm: "logk"},
+		{Graph: "p tw 3 2\n1 2\n2 3\n", Format: "gr", Width: 1, Preprocess: "gyo"},
+		{Graph: "E1(a,b,c),E2(c,d),E3(d,e,a),E4(e,b),E5(a,c).", Exact: true, Preprocess: "dedup,subsume,degree1"},
+	}
+	expected := []struct {
+		state string
+		width int
+	}{{service.Found, 2}, {service.NotFound, 1}, {service.Found, 2}, {service.Found, 1}, {service.Found, 2}}
+
+	for i, request := range requests {
+		var status service.Status
+		if code := serviceCall(t, http.MethodPost, server.URL+"/jobs", request, &status); code != http.StatusAccepted {
+			t.Fatalf("request %d: status %d", i, code)
+		}
+
+		status = awaitJob(t, server.URL, status.ID)
+		if status.State != expected[i].state || status.Result == nil || status.Result.Width != expected[i].width {
+			t.Errorf("request %d: expected %v at width %d, got %+v", i, expected[i].state, expected[i].width, status)
+			continue
+		}
+		if status.State == service.Found && (!status.Result.Correct || status.Result.Root == nil) {
+			t.Errorf("request %d: no correct HD in %+v", i, status.Result)
+		}
+	}
+
+	var list []service.Status
+	if serviceCall(t, http.MethodGet, server.URL+"/jobs", nil, &list); len(list) != len(requests) || list[0].ID != "1" {
+		t.Errorf("expected %d jobs in order, got %+v", len(requests), list)
+	}
+
+	invalid := []service.Request{
+		{Graph: "E1(a,b).", Width: 0},
+		{Graph: "E1(a,b).", Width: 1, Format: "xml"},
+		{Graph: "E1(a,b).", Width: 1, Algorithm: "magic"},
+		{Graph: "E1(a,b).", Width: 1, Preprocess: "hinge"},
+	}
+	for i, request := range invalid {
+		if code := serviceCall(t, http.MethodPost, server.URL+"/jobs", request, nil); code != http.StatusBadRequest {
+			t.Errorf("invalid request %d: expected status 400, got %d", i, code)
+		}
+	}
+
+	var status service.Status
+	serviceCall(t, http.MethodPost, server.URL+"/jobs", service.Request{Graph: "E1(a,b", Width: 1}, &status)
+	if status = awaitJob(t, server.URL, status.ID); status.State != service.Failed || status.Error == "" {
+		t.Errorf("expected malformed input to fail, got %+v", status)
+	}
+	if code := serviceCall(t, http.MethodGet, server.URL+"/jobs/999", nil, nil); code != http.StatusNotFound {
+		t.Errorf("expected status 404 for an unknown job, got %d", code)
+	}
+}
+
+//TestServiceLimits checks the bounded queue, the cancellation of running and queued jobs, and timeouts
+func TestServiceLimits(t *testing.T) {
+	s := service.New(service.Config{Workers: 1, QueueSize: 1})
+	defer s.Close()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	hard := service.Request{Graph: hardInstance(), Width: 3, Algorithm: "logk"}
+
+	var running, queued service.Status
+	serviceCall(t, http.MethodPost, server.URL+"/jobs", hard, &running)
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if status, _ := s.Status(running.ID); status.State == service.Running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("first job not started")
+		}
+	}
+	serviceCall(t, http.MethodPost, server.URL+"/jobs", hard, &queued)
+	if code := serviceCall(t, http.MethodPost, server.URL+"/jobs", hard, nil); code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 with a full queue, got %d", code)
+	}
+
+	serviceCall(t, http.MethodDelete, server.URL+"/jobs/"+queued.ID, nil, nil)
+	serviceCall(t, http.MethodDelete, server.URL+"/jobs/"+running.ID, nil, nil)
+	for _, id := range []string{running.ID, queued.ID} {
+		if status := awaitJob(t, server.URL, id); status.State != service.Cancelled {
+			t.Errorf("job %s: expected to be cancelled, got %+v", id, status)
+		}
+	}
+
+	hard.TimeoutMS = 100
+	var timed service.Status
+	serviceCall(t, http.MethodPost, server.URL+"/jobs", hard, &timed)
+	if status := awaitJob(t, server.URL, timed.ID); status.State != service.Timeout || status.Progress.Width != 3 {
+		t.Errorf("expected a timeout at width 3, got %+v", status)
+	}
+
+	// finished jobs are forgotten once deleted
+	serviceCall(t, http.MethodDelete, server.URL+"/jobs/"+timed.ID, nil, nil)
+	if code := serviceCall(t, http.MethodGet, server.URL+"/jobs/"+timed.ID, nil, nil); code != http.StatusNotFound {
+		t.Errorf("expected deleted job to be gone, got status %d", code)
+	}
+}
+
+// nodeNames collects the names in the bags and covers of a node and its descendants
+func nodeNames(n service.Node, names map[string]bool) {
+	for _, name := range append(append([]string{}, n.Bag...), n.Cover...) {
+		names[name] = true
+	}
+	for _, c := range n.Children {
+		nodeNames(c, names)
+	}
+}
+
+//TestServiceConcurrentParse checks that jobs parsed by several workers at once each keep the names and structure
+// of their own input
+func TestServiceConcurrentParse(t *testing.T) {
+	s := service.New(service.Config{Workers: 4, QueueSize: 32})
+	defer s.Close()
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	var ids []string
+	for i := 0; i < 32; i++ {
+		var graph string
+		for j := 0; j <= i%5+2; j++ {
+			graph += fmt.Sprintf("G%dE%d(g%dv%d,g%dv%d),", i, j, i, j, i, j+1)
+		}
+		graph += fmt.Sprintf("G%dC(g%dv0,g%dv%d).", i, i, i, i%5+3)
+
+		var status service.Status
+		if code := serviceCall(t, http.MethodPost, server.URL+"/jobs", service.Request{Graph: graph, Width: 2}, &status); code != http.StatusAccepted {
+			t.Fatalf("request %d: status %d", i, code)
+		}
+		ids = append(ids, status.ID)
+	}
+
+	for i, id := range ids {
+		status := awaitJob(t, server.URL, id)
+		if status.State != service.Found || !status.Result.Correct {
+			t.Errorf("request %d: expected a correct HD, got %+v", i, status)
+			continue
+		}
+
+		names := make(map[string]bool)
+		nodeNames(*status.Result.Root, names)
+		prefix := fmt.Sprintf("g%d", i)
+		for name := range names {
+			if name = strings.ToLower(name); !strings.HasPrefix(name, prefix) || !strings.ContainsAny(name[len(prefix):][:1], "vec") {
+				t.Errorf("request %d: name %s of another input", i, name)
+			}
+		}
+	}
+}
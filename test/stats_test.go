@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"testing"
+
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+// TestStats checks the statistics of a cyclic and an acyclic hypergraph
+func TestStats(t *testing.T) {
+	graph, _, err := logk.GetGraph("R(a,b), S(b,c), T(c,a), U(a,d), V(e,f).")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := logk.GetStats(graph, "cycle")
+	if s.Vertices != 6 || s.Edges != 5 || s.ArityMax != 2 || s.Arities[2] != 5 || s.DegreeMax != 3 {
+		t.Errorf("wrong counts: %+v", s)
+	}
+	if s.BIP != 1 || s.BMIP3 != 1 || s.VCDimension != 2 || !s.VCExact || s.Components != 2 {
+		t.Errorf("wrong intersections or components: %+v", s)
+	}
+	if s.AlphaAcyclic || s.HWLower != 2 || s.HWUpper < 2 || s.GHWUpper != 2 {
+		t.Errorf("wrong bounds: %+v", s)
+	}
+
+	acyclic, _, err := logk.GetGraph("R(a,b,c), S(c,d), T(d,e).")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = logk.GetStats(acyclic, "path")
+	if !s.AlphaAcyclic || s.HWLower != 1 || s.HWUpper != 1 || s.TypeCollapseSaved != 1 {
+		t.Errorf("wrong statistics for acyclic graph: %+v", s)
+	}
+}
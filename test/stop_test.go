@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"testing"
+
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+//TestStopFlag checks that stop flags are only stopped once told to, and that a nil flag never is
+func TestStopFlag(t *testing.T) {
+	var none *logk.StopFlag
+	if none.Stopped() {
+		t.Error("nil flag stopped")
+	}
+
+	flag := logk.StopFlag{}
+	if flag.Stopped() {
+		t.Error("flag stopped before Stop")
+	}
+
+	done := make(chan bool)
+	go func() {
+		flag.Stop()
+		done <- true
+	}()
+	<-done
+
+	if !flag.Stopped() {
+		t.Error("flag not stopped after Stop")
+	}
+}
+
+//TestStopFlagChild checks that stopping a child flag leaves its parent alone, while stopping the parent stops the child
+func TestStopFlagChild(t *testing.T) {
+	parent := logk.StopFlag{}
+	child := parent.Child()
+	other := parent.Child()
+
+	child.Stop()
+	if parent.Stopped() || other.Stopped() {
+		t.Error("stopping child stopped its parent or sibling")
+	}
+
+	grandchild := other.Child()
+	parent.Stop()
+	if !other.Stopped() || !grandchild.Stopped() {
+		t.Error("stopping parent didn't stop its descendants")
+	}
+
+	var none *logk.StopFlag
+	if none.Child().Stopped() {
+		t.Error("child of nil flag stopped")
+	}
+}
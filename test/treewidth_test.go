@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+//TestTreewidth checks parsing of PACE 2017 graphs, the min-fill heuristic on a grid, and writing and reading
+// tree decompositions
+func TestTreewidth(t *testing.T) {
+	input := "c a 3x3 grid, and an isolated vertex\np tw 10 12\n1 2\n2 3\n4 5\n5 6\n7 8\n8 9\n1 4\n4 7\n2 5\n5 8\n" +
+		"3 6\n6 9\n"
+
+	graph, encoding, err := logk.GetGraphGR(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graph.Vertices()) != 10 || graph.Edges.Len() != 13 {
+		t.Fatalf("wrong graph: %v", encoding.Graph(graph))
+	}
+	if _, _, err := logk.GetGraphGR("p tw 2 1\n1 3\n"); err == nil {
+		t.Error("edge with unknown vertex not rejected")
+	}
+
+	decomp := logk.MinFill(graph)
+	if !decomp.Correct(graph) || logk.TreeWidth(decomp) != 3 {
+		t.Errorf("min-fill produced wrong decomposition, of width %d:\n%v", logk.TreeWidth(decomp),
+			encoding.Decomp(decomp))
+	}
+
+	var buffer bytes.Buffer
+	if err := logk.WriteTD(&buffer, decomp, graph, encoding); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buffer.String(), "s td 10 4 10\n") {
+		t.Errorf("wrong header:\n%v", buffer.String())
+	}
+
+	read, err := logk.ReadTD(strings.NewReader(buffer.String()), graph, encoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !read.Correct(graph) || logk.TreeWidth(read) != 3 {
+		t.Errorf("decomposition changed when written and read:\n%v", encoding.Decomp(read))
+	}
+}
+
+//TestTreewidthSearch checks that LogKDecomp in treewidth mode finds tree decompositions of a grid and a cycle at
+// their treewidth, with bags limited by the width, and none below it
+func TestTreewidthSearch(t *testing.T) {
+	inputs := []struct {
+		graph     string
+		treewidth int
+	}{
+		{"p tw 9 12\n1 2\n2 3\n4 5\n5 6\n7 8\n8 9\n1 4\n4 7\n2 5\n5 8\n3 6\n6 9\n", 3},
+		{"p tw 5 5\n1 2\n2 3\n3 4\n4 5\n5 1\n", 2},
+	}
+
+	for _, input := range inputs {
+		graph, encoding, err := logk.GetGraphGR(input.graph)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		solver := logk.LogKDecomp{Graph: graph, BalFactor: 2, TreeDecomp: true}
+		solver.SetGenerator(lib.ParallelSearchGen{})
+
+		solver.SetWidth(input.treewidth + 1)
+		decomp := solver.FindDecomp()
+		decomp.Graph = graph
+		if !decomp.Correct(graph) || logk.TreeWidth(decomp) != input.treewidth {
+			t.Errorf("expected a tree decomposition of width %d, got:\n%v", input.treewidth, encoding.Decomp(decomp))
+		}
+
+		solver.SetWidth(input.treewidth)
+		if decomp := solver.FindDecomp(); !reflect.DeepEqual(decomp, lib.Decomp{}) {
+			t.Errorf("unexpected tree decomposition of width %d:\n%v", input.treewidth-1, encoding.Decomp(decomp))
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+// Treewidth mode, computing tree decompositions of plain graphs with LogKDecomp or a heuristic
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/cem-okulmus/BalancedGo/lib"
+	logk "github.com/cem-okulmus/log-k-decomp/lib"
+)
+
+// decomposeTW parses a single graph, and searches for a tree decomposition of it with the given options. The
+// width given in the options is the treewidth, i.e. one less than the size of the largest bag. The exit code is
+// returned.
+func decomposeTW(input string, opts options) int {
+	var graph Graph
+	var encoding logk.Encoding
+	var err error
+
+	switch {
+	case opts.gr:
+		graph, encoding, err = logk.GetGraphGR(input)
+	case opts.pace:
+		graph, encoding, err = logk.GetGraphPACE(input)
+	default:
+		graph, encoding, err = logk.GetGraph(input)
+	}
+	if err != nil {
+		fmt.Println("Skipping", opts.label+":", err)
+		return exitError
+	}
+	ctx := runContext{encoding: encoding, original: graph}
+
+	var times []labelTime
+	var decomp Decomp
+	var algorithm string
+
+	// the heuristic is always computed, as an upper bound for the exact search
+	start := time.Now()
+	heuristic := logk.MinFill(graph)
+	msec := time.Since(start).Seconds() * float64(time.Second/time.Millisecond)
+	times = append(times, labelTime{time: msec, label: "Min-fill heuristic"})
+
+	logK := logk.LogKDecomp{Graph: graph, BalFactor: opts.balFactor, TreeDecomp: true, Stop: opts.stop,
+		Memory: opts.memory}
+	logK.SetGenerator(lib.ParallelSearchGen{})
+
+	start = time.Now()
+	switch {
+	case opts.twHeuristic:
+		algorithm = "Min-fill heuristic"
+		decomp = heuristic
+	case opts.exact:
+		algorithm = "Min-fill heuristic, optimal according to " + logK.Name()
+		decomp = heuristic
+
+		// look for a decomposition below the width of the heuristic, starting from the smallest width, and keep
+		// the heuristic if the search is stopped
+		for w := 0; w < logk.TreeWidth(heuristic) && !opts.stop.Stopped(); w++ {
+			logK.SetWidth(w + 1)
+			if found := logK.FindDecomp(); !reflect.DeepEqual(found, Decomp{}) {
+				algorithm = logK.Name()
+				decomp = found
+				break
+			}
+		}
+		if opts.stop.Stopped() && algorithm != logK.Name() {
+			algorithm = "Min-fill heuristic, search stopped"
+		}
+	default:
+		if logk.TreeWidth(heuristic) <= opts.width {
+			algorithm = "Min-fill heuristic"
+			decomp = heuristic // no need to search any further
+		} else {
+			algorithm = logK.Name()
+			logK.SetWidth(opts.width + 1)
+			decomp = logK.FindDecomp()
+		}
+	}
+	if opts.stop.Stopped() && reflect.DeepEqual(decomp, Decomp{}) {
+		return reportStopped(opts)
+	}
+	if !opts.twHeuristic {
+		msec = time.Since(start).Seconds() * float64(time.Second/time.Millisecond)
+		times = append(times, labelTime{time: msec, label: "Decomposition"})
+	}
+
+	if !reflect.DeepEqual(decomp, Decomp{}) {
+		decomp.Graph = graph
+	}
+	decomp = normaliseDecomp(decomp, ctx, opts)
+
+	width := opts.width
+	if opts.exact || opts.twHeuristic {
+		width = logk.TreeWidth(decomp)
+	}
+
+	fmt.Println("Used algorithm: " + algorithm)
+	if opts.bench {
+		fmt.Println("Result ( ran with treewidth", width, ")")
+	} else {
+		fmt.Println("Result ( ran with treewidth", width, ")\n", ctx.encoding.Decomp(decomp))
+	}
+
+	var sumTotal float64
+	for _, time := range times {
+		sumTotal = sumTotal + time.time
+	}
+	fmt.Printf("Time: %.5f ms\n", sumTotal)
+
+	fmt.Println("Time Composition: ")
+	for _, time := range times {
+		fmt.Println(time)
+	}
+
+	correct := decomp.Correct(graph)
+	if correct {
+		fmt.Println("\nTreewidth: ", logk.TreeWidth(decomp))
+	} else {
+		fmt.Println("\nTreewidth: ", "-")
+	}
+	fmt.Println("Correct: ", correct)
+
+	if correct && len(opts.gml) > 0 {
+		f, err := os.Create(opts.gml)
+		check(err)
+		f.WriteString(ctx.encoding.GML(decomp))
+		f.Close()
+	}
+	if correct && len(opts.tdPath) > 0 {
+		f, err := os.Create(opts.tdPath)
+		check(err)
+		check(logk.WriteTD(f, decomp, graph, encoding))
+		f.Close()
+	}
+
+	if !correct {
+		return exitNotFound
+	}
+	return exitOK
+}